@@ -3,44 +3,161 @@ package app
 import (
 	"cmp"
 	"context"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
 	"regexp"
 	"slices"
 	"strings"
+	"time"
 
 	"github.com/chtozamm/dotfiles-collector/internal/database"
 	"github.com/chtozamm/dotfiles-collector/internal/fileops"
 )
 
-// CopyFiles prepares source paths and copies them to the destination.
-func (app *Application) CopyFiles() error {
-	paths, err := app.GetCollectPaths()
+// CopyFiles prepares source paths, copies them to the destination
+// and returns the summary of the run.
+//
+// If ContinueOnError is set, the failures of all sources are returned
+// as fileops.Errors together with the result.
+func (app *Application) CopyFiles(opts CollectOptions) (*CollectResult, error) {
+	return app.collect(opts, false)
+}
+
+// PlanCopyFiles prepares source paths and returns the summary of changes
+// CopyFiles would make to the destination without touching the disk.
+func (app *Application) PlanCopyFiles(opts CollectOptions) (*CollectResult, error) {
+	return app.collect(opts, true)
+}
+
+// collect copies source paths to the destination or only plans the copy if dryRun is set.
+func (app *Application) collect(collectOpts CollectOptions, dryRun bool) (*CollectResult, error) {
+	start := time.Now()
+
+	paths, err := app.getEnabledPaths()
 	if err != nil {
-		return fmt.Errorf("get paths: %v", err)
+		return nil, fmt.Errorf("get paths: %v", err)
 	}
 
 	if len(paths) == 0 {
-		return fmt.Errorf("no paths found in database")
+		return nil, ErrNoPaths
 	}
 
-	ignorePatterns, err := app.GetIgnorePatterns()
+	patterns, err := app.GetIgnorePatterns()
 	if err != nil {
-		return fmt.Errorf("get ignore patterns: %v", err)
+		return nil, fmt.Errorf("get ignore patterns: %v", err)
 	}
 
-	for _, src := range paths {
+	// Compile the patterns of every source before copying anything,
+	// so that an invalid pattern doesn't leave the collection half done
+	matchers := make([]*fileops.Matcher, len(paths))
+	for i, src := range paths {
+		matchers[i], err = fileops.NewMatcher(sourcePatterns(patterns, src))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Check every source before copying anything as well. The first of
+	// the colliding sources is collected, the others are refused.
+	policies := make([]fileops.SymlinkPolicy, len(paths))
+	checkErrs := make([]error, len(paths))
+	result := &CollectResult{Plan: &fileops.Plan{}}
+	for i, src := range paths {
+		policies[i] = collectOpts.Symlinks
+		if src.Symlinks != "" {
+			policies[i], err = fileops.ParseSymlinkPolicy(src.Symlinks)
+			if err != nil {
+				return nil, fmt.Errorf("path %s: %v", src.Path, err)
+			}
+		}
+
+		warnings, err := app.checkSource(src.Path)
+		if err == nil {
+			err = app.checkCollision(src.Path, src.Subdir, paths[:i])
+		}
+		if err != nil && !collectOpts.ContinueOnError {
+			return nil, err
+		}
+		checkErrs[i] = err
+		result.Warnings = append(result.Warnings, warnings...)
+	}
+
+	opts := fileops.Options{
+		Overwrite:       true,
+		CreateDst:       true,
+		Checksum:        collectOpts.Checksum,
+		Metadata:        collectOpts.Metadata,
+		Jobs:            collectOpts.Jobs,
+		Mirror:          collectOpts.Mirror,
+		ContinueOnError: collectOpts.ContinueOnError,
+		Exclude:         app.excludedDirs(),
+	}
+
+	// The sources are copied together, so that they share the workers
+	// and mirror mode keeps the files every one of them has written
+	sources := make([]fileops.Source, 0, len(paths))
+	for i, src := range paths {
+		if checkErrs[i] != nil {
+			continue
+		}
 		dstPath := app.Destination
 		// Append parent directory name to destination if specified
 		if src.Subdir != "." {
 			dstPath = filepath.Join(app.Destination, src.Subdir)
 		}
-		if err := fileops.Copy(src.Path, dstPath, true, true, ignorePatterns); err != nil {
-			return fmt.Errorf("copy %s: %v", src.Path, err)
+		sources = append(sources, fileops.Source{Src: src.Path, Dst: dstPath, Ignore: matchers[i], Symlinks: policies[i]})
+	}
+	var plans []*fileops.Plan
+	var copyErrs []error
+	if dryRun {
+		plans, copyErrs = fileops.PlanCopyAll(sources, opts)
+	} else {
+		plans, copyErrs = fileops.CopyAll(sources, opts)
+	}
+
+	var errs fileops.Errors
+	copied := 0
+	for i, src := range paths {
+		if checkErrs[i] != nil {
+			errs = append(errs, fileops.NewFileError(src.Path, checkErrs[i]))
+			result.Sources = append(result.Sources, SourceResult{Path: src.Path, Failed: 1})
+			continue
 		}
+
+		srcPlan, err := plans[copied], copyErrs[copied]
+		copied++
+		result.Plan.Merge(srcPlan)
+		srcResult := newSourceResult(src.Path, srcPlan)
+		if err != nil {
+			if !collectOpts.ContinueOnError {
+				return nil, fmt.Errorf("copy %s: %v", src.Path, err)
+			}
+			var srcErrs fileops.Errors
+			if errors.As(err, &srcErrs) {
+				errs = append(errs, srcErrs...)
+			} else {
+				// The source couldn't be walked at all
+				errs = append(errs, fileops.NewFileError(src.Path, err))
+				srcResult.Failed++
+			}
+		}
+		result.Sources = append(result.Sources, srcResult)
 	}
-	return nil
+
+	result.Warnings = append(result.Warnings, result.Plan.Warnings...)
+	for _, collision := range result.Plan.Collisions() {
+		result.Warnings = append(result.Warnings, fmt.Sprintf(
+			"%s collides with %s at %s", collision[1].Src, collision[0].Src, collision[1].Dst,
+		))
+	}
+	result.Duration = time.Since(start)
+
+	if len(errs) > 0 {
+		return result, errs
+	}
+	return result, nil
 }
 
 // GetCollectPaths returns a list of source paths added to the collector.
@@ -51,7 +168,19 @@ func (app *Application) GetCollectPaths() ([]SourcePath, error) {
 		return nil, fmt.Errorf("get collect paths: %v", err)
 	}
 	for _, path := range collectPaths {
-		paths = append(paths, SourcePath{ID: path.ID, Path: path.Path, Subdir: path.ParentDir})
+		// The portable form follows the database to other machines, where the resolved path is wrong
+		resolved := path.Path
+		if expanded, ok := expandPortable(path.Portable); ok {
+			resolved = expanded
+		}
+		paths = append(paths, SourcePath{
+			ID:       path.ID,
+			Path:     resolved,
+			Subdir:   path.ParentDir,
+			Symlinks: path.Symlinks,
+			Enabled:  path.Enabled,
+			Portable: path.Portable,
+		})
 	}
 
 	slices.SortFunc(paths, func(a, b SourcePath) int {
@@ -61,26 +190,123 @@ func (app *Application) GetCollectPaths() ([]SourcePath, error) {
 	return paths, nil
 }
 
-// GetIgnorePatterns returns a list of ignore patterns added to the collector.
-func (app *Application) GetIgnorePatterns() ([]string, error) {
-	patterns := []string{}
+// getEnabledPaths returns the source paths that are collected.
+func (app *Application) getEnabledPaths() ([]SourcePath, error) {
+	paths, err := app.GetCollectPaths()
+	if err != nil {
+		return nil, err
+	}
+	enabled := paths[:0]
+	for _, path := range paths {
+		if path.Enabled {
+			enabled = append(enabled, path)
+		}
+	}
+	return enabled, nil
+}
+
+// SetCollectPathEnabled enables or disables a source path. Disabled paths
+// stay in the collector, but aren't collected.
+func (app *Application) SetCollectPathEnabled(pathname string, enabled bool) error {
+	path, err := app.getCollectPath(pathname)
+	if err != nil {
+		return err
+	}
+	err = app.DB.SetCollectPathEnabled(context.Background(), database.SetCollectPathEnabledParams{Enabled: enabled, ID: path.ID})
+	if err != nil {
+		return fmt.Errorf("update path %s: %v", pathname, err)
+	}
+	return nil
+}
+
+// SetIgnorePatternEnabled enables or disables an ignore pattern of the given kind.
+// If source is not empty, the pattern scoped to that source path is changed.
+// Disabled patterns stay in the collector, but don't ignore anything.
+func (app *Application) SetIgnorePatternEnabled(pattern string, kind fileops.PatternKind, source string, enabled bool) error {
+	sourceID, err := app.sourceID(source)
+	if err != nil {
+		return err
+	}
+	updated, err := app.DB.SetIgnorePatternEnabled(context.Background(), database.SetIgnorePatternEnabledParams{
+		Enabled:  enabled,
+		Pattern:  pattern,
+		Kind:     kind.String(),
+		SourceID: sourceID,
+	})
+	if err != nil {
+		return fmt.Errorf("update pattern %s: %v", pattern, err)
+	}
+	if updated == 0 {
+		return fmt.Errorf("%s pattern %s does not exist", kind, pattern)
+	}
+	return nil
+}
+
+// GetIgnorePatterns returns a list of ignore patterns added to the collector
+// in the order they were added, which is the order they are evaluated in.
+func (app *Application) GetIgnorePatterns() ([]IgnorePattern, error) {
+	patterns := []IgnorePattern{}
 	patternsEntries, err := app.DB.GetIgnorePatterns(context.Background())
 	if err != nil {
 		return nil, fmt.Errorf("get ignore patterns: %v", err)
 	}
 	for _, pattern := range patternsEntries {
-		patterns = append(patterns, pattern.Pattern)
+		kind, err := fileops.ParsePatternKind(pattern.Kind)
+		if err != nil {
+			return nil, fmt.Errorf("pattern %s: %v", pattern.Pattern, err)
+		}
+		patterns = append(patterns, IgnorePattern{
+			ID:       pattern.ID,
+			Pattern:  pattern.Pattern,
+			Kind:     kind,
+			SourceID: pattern.SourceID,
+			Preset:   pattern.Preset,
+			Enabled:  pattern.Enabled,
+		})
 	}
 
-	slices.SortFunc(patterns, func(a, b string) int {
-		return cmp.Compare(a, b)
-	})
-
 	return patterns, nil
 }
 
 // AddCollectPath adds a new path to the collector.
-func (app *Application) AddCollectPath(path, parentDir string) error {
+// If symlinks is not empty, it sets the symlink policy for the path.
+//
+// Along with the resolved path, the path is stored in a portable form, e.g.
+// "~/.bashrc" or "$XDG_CONFIG_HOME/nvim", which is expanded when the paths
+// are read, so the database can be moved to another machine. Variables used
+// in the given path are kept in the portable form if they contain the path.
+//
+// Paths inside the destination or the data directory are refused. If either
+// of them is inside the path, it is excluded from the collection and
+// a warning about it is returned.
+func (app *Application) AddCollectPath(path, parentDir, symlinks string) ([]string, error) {
+	params, warnings, err := app.prepareCollectPath(path, parentDir, symlinks)
+	if err != nil {
+		return nil, err
+	}
+
+	// Add the path to the database
+	err = app.DB.AddCollectPath(context.Background(), params)
+	if err != nil {
+		return nil, fmt.Errorf("add path %s: %v", params.Path, err)
+	}
+
+	return warnings, nil
+}
+
+// prepareCollectPath parses, resolves and checks a path before it is added,
+// as described in AddCollectPath. It returns the row to add and the warnings
+// about the path.
+func (app *Application) prepareCollectPath(path, parentDir, symlinks string) (database.AddCollectPathParams, []string, error) {
+	var none database.AddCollectPathParams
+	if symlinks != "" {
+		if _, err := fileops.ParseSymlinkPolicy(symlinks); err != nil {
+			return none, nil, err
+		}
+	}
+
+	var vars []string
+
 	if parentDir == "" {
 		// Check for "->" in the given path: if it is provided,
 		// the left side is path, the right side is parentDir
@@ -95,9 +321,10 @@ func (app *Application) AddCollectPath(path, parentDir string) error {
 			for _, match := range matches[1:] {
 				env, found := os.LookupEnv(match[1:])
 				if !found {
-					return fmt.Errorf("path contains env %s, but it cannot be retrieved", match)
+					return none, nil, fmt.Errorf("path contains env %s, but it cannot be retrieved", match)
 				}
 				path = strings.ReplaceAll(path, match, env)
+				vars = append(vars, match[1:])
 			}
 		}
 	}
@@ -112,83 +339,132 @@ func (app *Application) AddCollectPath(path, parentDir string) error {
 	// Check if the path exists
 	if _, err := os.Stat(path); err != nil {
 		if os.IsNotExist(err) {
-			return fmt.Errorf("path does not exist: %s", path)
+			return none, nil, fmt.Errorf("path does not exist: %s", path)
 		}
-		return fmt.Errorf("check path %s: %v", path, err)
+		return none, nil, fmt.Errorf("check path %s: %v", path, err)
 	}
 
 	// Get the absolute path with correct case
 	absolutePath, err := filepath.Abs(path)
 	if err != nil {
-		return fmt.Errorf("get absolute path for %s: %v", path, err)
+		return none, nil, fmt.Errorf("get absolute path for %s: %v", path, err)
 	}
 
 	// Resolve symlinks to ensure correct case
 	resolvedPath, err := filepath.EvalSymlinks(absolutePath)
 	if err != nil {
-		return fmt.Errorf("resolve symlinks for %s: %v", absolutePath, err)
+		return none, nil, fmt.Errorf("resolve symlinks for %s: %v", absolutePath, err)
 	}
 	path = resolvedPath
 
+	// Check that the path doesn't overlap with the collector's own files
+	warnings, err := app.checkSource(path)
+	if err != nil {
+		return none, nil, err
+	}
+
 	// Check if path already added
-	_, err = app.DB.GetCollectPath(context.Background(), path)
+	_, err = app.getCollectPath(path)
 	if err == nil {
-		return fmt.Errorf("path %s already exists", path)
+		return none, nil, fmt.Errorf("path %s already exists", path)
 	}
 
-	// Add the path to the database
-	err = app.DB.AddCollectPath(context.Background(), database.AddCollectPathParams{Path: path, ParentDir: parentDir})
+	// Check that the path doesn't overwrite files of another source
+	sources, err := app.GetCollectPaths()
 	if err != nil {
-		return fmt.Errorf("add path %s: %v", path, err)
+		return none, nil, err
+	}
+	if err := app.checkCollision(path, parentDir, sources); err != nil {
+		return none, nil, err
 	}
 
-	return nil
+	return database.AddCollectPathParams{
+		Path:      path,
+		Portable:  portablePath(path, vars),
+		ParentDir: parentDir,
+		Symlinks:  symlinks,
+	}, warnings, nil
 }
 
-// AddIgnorePattern adds an ignore pattern to the collector.
-func (app *Application) AddIgnorePattern(pattern string) error {
-	// Try to compile regex before proceeding
-	_, err := regexp.Compile(pattern)
+// AddIgnorePattern adds an ignore pattern of the given kind to the collector.
+// If source is not empty, the pattern applies only to that source path.
+func (app *Application) AddIgnorePattern(pattern string, kind fileops.PatternKind, source string) error {
+	// Try to compile the pattern before proceeding
+	err := fileops.IgnorePattern{Pattern: pattern, Kind: kind}.Validate()
+	if err != nil {
+		return err
+	}
+
+	sourceID, err := app.sourceID(source)
 	if err != nil {
 		return err
 	}
 
+	return addIgnorePattern(app.DB, pattern, kind, sourceID, "")
+}
+
+// errPatternExists is returned when adding a pattern that is already added.
+var errPatternExists = errors.New("already exists")
+
+// addIgnorePattern stores a valid pattern, optionally as a part of the preset.
+func addIgnorePattern(db *database.Queries, pattern string, kind fileops.PatternKind, sourceID int64, preset string) error {
 	// Check if pattern already added
-	_, err = app.DB.GetIgnorePattern(context.Background(), pattern)
+	_, err := db.GetIgnorePattern(context.Background(), database.GetIgnorePatternParams{
+		Pattern:  pattern,
+		Kind:     kind.String(),
+		SourceID: sourceID,
+	})
 	if err == nil {
-		return fmt.Errorf("pattern %s already exists", pattern)
+		return fmt.Errorf("pattern %s %w", pattern, errPatternExists)
 	}
 
-	err = app.DB.AddIgnorePattern(context.Background(), pattern)
+	err = db.AddIgnorePattern(context.Background(), database.AddIgnorePatternParams{
+		Pattern:  pattern,
+		Kind:     kind.String(),
+		SourceID: sourceID,
+		Preset:   preset,
+	})
 	if err != nil {
 		return fmt.Errorf("add pattern %s: %v", pattern, err)
 	}
 	return nil
 }
 
-// RemoveCollectPath removes a source path from the collector.
+// RemoveCollectPath removes a source path and its ignore patterns from the collector.
 func (app *Application) RemoveCollectPath(pathname string) error {
-	_, err := app.DB.GetCollectPath(context.Background(), pathname)
+	path, err := app.getCollectPath(pathname)
 	if err != nil {
-		return fmt.Errorf("path %s does not exist", pathname)
+		return err
+	}
+	err = app.DB.RemoveSourceIgnorePatterns(context.Background(), path.ID)
+	if err != nil {
+		return fmt.Errorf("remove ignore patterns of path %s: %v", pathname, err)
 	}
-	err = app.DB.RemoveCollectPath(context.Background(), pathname)
+	err = app.DB.RemoveCollectPath(context.Background(), path.ID)
 	if err != nil {
 		return fmt.Errorf("remove path %s: %v", pathname, err)
 	}
 	return nil
 }
 
-// RemoveIgnorePattern removes an ignore pattern from the collector.
-func (app *Application) RemoveIgnorePattern(patternString string) error {
-	_, err := app.DB.GetIgnorePattern(context.Background(), patternString)
+// RemoveIgnorePattern removes an ignore pattern of the given kind from the collector.
+// If source is not empty, the pattern is removed from that source path.
+func (app *Application) RemoveIgnorePattern(patternString string, kind fileops.PatternKind, source string) error {
+	sourceID, err := app.sourceID(source)
 	if err != nil {
-		return fmt.Errorf("pattern %s does not exist", patternString)
+		return err
 	}
-	err = app.DB.RemoveIgnorePattern(context.Background(), patternString)
+	removed, err := app.DB.RemoveIgnorePattern(context.Background(), database.RemoveIgnorePatternParams{
+		Pattern:  patternString,
+		Kind:     kind.String(),
+		SourceID: sourceID,
+	})
 	if err != nil {
 		return fmt.Errorf("remove pattern %s: %v", patternString, err)
 	}
+	if removed == 0 {
+		return fmt.Errorf("%s pattern %s does not exist", kind, patternString)
+	}
 	return nil
 }
 
@@ -203,10 +479,10 @@ func (app *Application) RemoveCollectPaths(pathnames []string) error {
 	return nil
 }
 
-// RemoveIgnorePatterns removes the given patterns from the database.
-func (app *Application) RemoveIgnorePatterns(patterns []string) error {
+// RemoveIgnorePatterns removes the given global patterns from the database.
+func (app *Application) RemoveIgnorePatterns(patterns []IgnorePattern) error {
 	for _, pattern := range patterns {
-		err := app.RemoveIgnorePattern(pattern)
+		err := app.RemoveIgnorePattern(pattern.Pattern, pattern.Kind, "")
 		if err != nil {
 			return err
 		}
@@ -0,0 +1,65 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/chtozamm/dotfiles-collector/internal/fileops"
+)
+
+// AdoptPath adds a path to the collector, moves it into the destination and
+// links the moved files back to their original location, the way LinkFiles
+// does. The path and parentDir are interpreted as in AddCollectPath.
+//
+// Either every step succeeds or none of them is kept: the path is added in
+// a transaction that is committed last, and if linking or committing fails,
+// the links are removed and the files are moved back.
+func (app *Application) AdoptPath(pathname, parentDir string) ([]string, error) {
+	params, warnings, err := app.prepareCollectPath(pathname, parentDir, "")
+	if err != nil {
+		return nil, err
+	}
+	path := params.Path
+
+	target := app.target(path, params.ParentDir)
+	if _, err := os.Lstat(target); err == nil {
+		return nil, fmt.Errorf("%s already exists in the destination", target)
+	}
+
+	tx, err := app.Conn.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	err = app.DB.WithTx(tx).AddCollectPath(context.Background(), params)
+	if err != nil {
+		return nil, fmt.Errorf("add path %s: %v", path, err)
+	}
+
+	if err := fileops.Move(path, filepath.Dir(target)); err != nil {
+		return nil, fmt.Errorf("move %s: %v", path, err)
+	}
+
+	// Once the files are moved, only links made by Link can be at the path
+	undo := func(err error) error {
+		if rmErr := os.RemoveAll(path); rmErr != nil {
+			return fmt.Errorf("%v, and the files are left in %s: %v", err, target, rmErr)
+		}
+		if mvErr := fileops.Move(target, filepath.Dir(path)); mvErr != nil {
+			return fmt.Errorf("%v, and the files are left in %s: %v", err, target, mvErr)
+		}
+		return err
+	}
+
+	if _, err := fileops.Link(target, filepath.Dir(path), fileops.Options{}); err != nil {
+		return nil, undo(fmt.Errorf("link %s: %v", path, err))
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, undo(fmt.Errorf("commit transaction: %v", err))
+	}
+	return warnings, nil
+}
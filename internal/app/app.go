@@ -1,19 +1,46 @@
 package app
 
 import (
+	"database/sql"
+
 	"github.com/chtozamm/dotfiles-collector/internal/database"
+	"github.com/chtozamm/dotfiles-collector/internal/fileops"
 )
 
 // SourcePath represents a path to collect files from.
 type SourcePath struct {
-	ID     int64
-	Path   string
-	Subdir string
+	ID       int64
+	Path     string
+	Subdir   string
+	Symlinks string // Symlink policy for this path, empty if the policy of the run applies
+	Enabled  bool   // Disabled paths are kept, but not collected
+	Portable string // Path as stored, with placeholders for the home directory and variables, empty if it has none
+}
+
+// IgnorePattern represents a pattern for paths the collector skips.
+type IgnorePattern struct {
+	ID       int64
+	Pattern  string
+	Kind     fileops.PatternKind
+	SourceID int64  // Source path the pattern is scoped to, zero if it applies to every source
+	Preset   string // Preset the pattern was added with, empty if it was added on its own
+	Enabled  bool   // Disabled patterns are kept, but don't ignore anything
+}
+
+// CollectOptions configures a single run of the collector.
+type CollectOptions struct {
+	Checksum        bool                  // Detect unchanged files by their contents instead of size and modification time
+	Symlinks        fileops.SymlinkPolicy // Symlink policy for paths that don't specify their own
+	Metadata        bool                  // Preserve modes, times and extended attributes of collected files
+	Jobs            int                   // Number of files copied in parallel
+	Mirror          bool                  // Remove collected files that no longer exist in the sources
+	ContinueOnError bool                  // Collect everything possible and report all failures at the end
 }
 
 // Application is the heart of the Dotfiles Collector application.
 type Application struct {
 	DB          *database.Queries // Interface for executing database queries.
+	Conn        *sql.DB           // Database connection, used to run queries in a transaction.
 	DataDir     string            // Directory where the application data is stored.
 	Destination string            // Directory where the collected files are copied.
 	Name        string            // Name of the application.
@@ -0,0 +1,162 @@
+package app
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"github.com/chtozamm/dotfiles-collector/internal/fileops"
+)
+
+// backupTimeFormat is the format of the backup names, which sort by time.
+const backupTimeFormat = "20060102-150405.000"
+
+// Backup is a set of files saved before a run of restore or link replaced them.
+type Backup struct {
+	ID    string    // Name of the backup, the time it was made in backupTimeFormat
+	Time  time.Time // Time the backup was made
+	Files []string  // Original paths of the saved files
+}
+
+// backupsDir returns the directory the backups are stored in.
+func (app *Application) backupsDir() string {
+	return filepath.Join(app.DataDir, "backups")
+}
+
+// newBackupDir returns the directory for the files replaced by a run that
+// starts now. The directory is created only once a file is saved to it.
+func (app *Application) newBackupDir() string {
+	return filepath.Join(app.backupsDir(), time.Now().Format(backupTimeFormat))
+}
+
+// GetBackups returns the backups of replaced files, the newest first.
+func (app *Application) GetBackups() ([]Backup, error) {
+	entries, err := os.ReadDir(app.backupsDir())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("read backups: %v", err)
+	}
+
+	backups := make([]Backup, 0, len(entries))
+	for _, entry := range entries {
+		backupTime, err := time.ParseInLocation(backupTimeFormat, entry.Name(), time.Local)
+		if !entry.IsDir() || err != nil {
+			continue
+		}
+		backup := Backup{ID: entry.Name(), Time: backupTime}
+		dir := filepath.Join(app.backupsDir(), entry.Name())
+		err = filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+			if err != nil || d.IsDir() {
+				return err
+			}
+			original, err := fileops.OriginalPath(dir, path)
+			if err != nil {
+				return err
+			}
+			backup.Files = append(backup.Files, original)
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("read backup %s: %v", backup.ID, err)
+		}
+		backups = append(backups, backup)
+	}
+
+	slices.Reverse(backups)
+	return backups, nil
+}
+
+// getBackup returns the backup with the given ID.
+func (app *Application) getBackup(id string) (Backup, error) {
+	backups, err := app.GetBackups()
+	if err != nil {
+		return Backup{}, err
+	}
+	for _, backup := range backups {
+		if backup.ID == id {
+			return backup, nil
+		}
+	}
+	return Backup{}, fmt.Errorf("backup %s does not exist", id)
+}
+
+// RestoreBackup copies the files saved in the backup back to their original
+// paths, or only the given ones if paths is not empty. The files it replaces
+// are backed up in turn, so restoring a backup can be undone as well.
+func (app *Application) RestoreBackup(id string, paths []string) (*CollectResult, error) {
+	start := time.Now()
+
+	backup, err := app.getBackup(id)
+	if err != nil {
+		return nil, err
+	}
+
+	files := backup.Files
+	if len(paths) > 0 {
+		files = nil
+		for _, path := range paths {
+			abs, _ := filepath.Abs(path)
+			if !slices.Contains(backup.Files, abs) {
+				return nil, fmt.Errorf("file %s is not in backup %s", path, id)
+			}
+			files = append(files, abs)
+		}
+	}
+
+	opts := fileops.Options{
+		Overwrite: true,
+		CreateDst: true,
+		Symlinks:  fileops.SymlinkPreserve,
+		Metadata:  true,
+		Backup:    app.newBackupDir(),
+	}
+	dir := filepath.Join(app.backupsDir(), backup.ID)
+	result := &CollectResult{Plan: &fileops.Plan{}}
+	for _, file := range files {
+		// The saved file has the name of the original, so it is copied into its parent directory
+		plan, err := fileops.Copy(fileops.BackupPath(dir, file), filepath.Dir(file), opts)
+		result.Plan.Merge(plan)
+		result.Sources = append(result.Sources, newSourceResult(file, plan))
+		if err != nil {
+			return nil, fmt.Errorf("restore %s: %v", file, err)
+		}
+	}
+	result.Backup = existingDir(opts.Backup)
+	result.Duration = time.Since(start)
+	return result, nil
+}
+
+// PruneBackups removes the backups beyond the newest keep ones, if keep is
+// positive, and the backups older than olderThan, if it is positive. It returns
+// the IDs of the removed backups.
+func (app *Application) PruneBackups(keep int, olderThan time.Duration) ([]string, error) {
+	backups, err := app.GetBackups()
+	if err != nil {
+		return nil, err
+	}
+
+	var removed []string
+	for i, backup := range backups {
+		if (keep <= 0 || i < keep) && (olderThan <= 0 || time.Since(backup.Time) <= olderThan) {
+			continue
+		}
+		if err := os.RemoveAll(filepath.Join(app.backupsDir(), backup.ID)); err != nil {
+			return removed, fmt.Errorf("remove backup %s: %v", backup.ID, err)
+		}
+		removed = append(removed, backup.ID)
+	}
+	return removed, nil
+}
+
+// existingDir returns the directory if it exists, or an empty string otherwise.
+func existingDir(dir string) string {
+	if info, err := os.Stat(dir); err == nil && info.IsDir() {
+		return dir
+	}
+	return ""
+}
@@ -28,11 +28,98 @@ func (app *Application) SetupDB() error {
 		return fmt.Errorf("set up database: %v", err)
 	}
 
+	// Bring the schema of an existing database up to date
+	if err := migrate(db); err != nil {
+		return fmt.Errorf("migrate database: %v", err)
+	}
+
 	app.DB = database.New(db)
+	app.Conn = db
+
+	// Store the portable form of paths added by earlier versions
+	if err := app.fillPortablePaths(); err != nil {
+		return fmt.Errorf("migrate database: %v", err)
+	}
 
 	return nil
 }
 
+// migrate applies migrations that haven't been applied to the database yet.
+// The number of applied migrations is stored in the user_version pragma.
+func migrate(db *sql.DB) error {
+	var version int
+	if err := db.QueryRow("PRAGMA user_version").Scan(&version); err != nil {
+		return fmt.Errorf("get schema version: %v", err)
+	}
+
+	for i := version; i < len(migrations); i++ {
+		tx, err := db.Begin()
+		if err != nil {
+			return fmt.Errorf("begin transaction: %v", err)
+		}
+		if _, err := tx.Exec(migrations[i]); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("apply migration %d: %v", i+1, err)
+		}
+		if _, err := tx.Exec(fmt.Sprintf("PRAGMA user_version = %d", i+1)); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("set schema version: %v", err)
+		}
+		if err := tx.Commit(); err != nil {
+			return fmt.Errorf("commit migration %d: %v", i+1, err)
+		}
+	}
+
+	return nil
+}
+
+// migrations change the initial schema, they must only ever be appended.
+var migrations = []string{
+	// Per-path policy for symbolic links, empty uses the policy of the run
+	`ALTER TABLE collect_paths ADD COLUMN symlinks TEXT NOT NULL DEFAULT '';`,
+	// Kind of the ignore pattern, either regex or glob. The table is rebuilt,
+	// because the same pattern can be added as a regex and as a glob.
+	`CREATE TABLE ignore_patterns_new (
+  id         INTEGER PRIMARY KEY,
+  pattern    TEXT NOT NULL,
+  kind       TEXT NOT NULL DEFAULT 'regex',
+  created_at TEXT NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%fZ', 'now')),
+  UNIQUE (pattern, kind)
+);
+INSERT INTO ignore_patterns_new (id, pattern, created_at)
+SELECT id, pattern, created_at FROM ignore_patterns;
+DROP TABLE ignore_patterns;
+ALTER TABLE ignore_patterns_new RENAME TO ignore_patterns;`,
+	// Source the ignore pattern is scoped to, zero for patterns that apply to every source.
+	// The table is rebuilt, because the same pattern can now be added to several sources.
+	`CREATE TABLE ignore_patterns_new (
+  id         INTEGER PRIMARY KEY,
+  pattern    TEXT NOT NULL,
+  kind       TEXT NOT NULL DEFAULT 'regex',
+  source_id  INTEGER NOT NULL DEFAULT 0,
+  created_at TEXT NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%fZ', 'now')),
+  UNIQUE (pattern, kind, source_id)
+);
+INSERT INTO ignore_patterns_new (id, pattern, kind, created_at)
+SELECT id, pattern, kind, created_at FROM ignore_patterns;
+DROP TABLE ignore_patterns;
+ALTER TABLE ignore_patterns_new RENAME TO ignore_patterns;`,
+	// Preset the ignore pattern was added with, empty for patterns added one by one.
+	// Added presets are stored on their own, so that a preset stays added even if
+	// all of its patterns were added before.
+	`ALTER TABLE ignore_patterns ADD COLUMN preset TEXT NOT NULL DEFAULT '';
+CREATE TABLE presets (
+  id         INTEGER PRIMARY KEY,
+  name       TEXT NOT NULL UNIQUE,
+  created_at TEXT NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%fZ', 'now'))
+);`,
+	// Disabled paths and patterns are kept, but left out of the collection
+	`ALTER TABLE collect_paths ADD COLUMN enabled BOOLEAN NOT NULL DEFAULT TRUE;
+ALTER TABLE ignore_patterns ADD COLUMN enabled BOOLEAN NOT NULL DEFAULT TRUE;`,
+	// Path with the home directory and variables as placeholders, so that it can be used on other machines
+	`ALTER TABLE collect_paths ADD COLUMN portable TEXT NOT NULL DEFAULT '';`,
+}
+
 var schema = `
 CREATE TABLE IF NOT EXISTS collect_paths (
   id         INTEGER PRIMARY KEY,
@@ -0,0 +1,65 @@
+package app
+
+import (
+	"fmt"
+
+	"github.com/chtozamm/dotfiles-collector/internal/fileops"
+)
+
+// IgnoreDecision explains whether a path is collected from a source path.
+type IgnoreDecision struct {
+	Source string // Source path the decision is made for
+	fileops.Decision
+}
+
+// ExplainIgnore returns whether the path is ignored and which pattern decides it,
+// for every source path the path is found in. It uses the same patterns and
+// options as CopyFiles with the given symlink policy, so a path found in no
+// source path has no decisions.
+func (app *Application) ExplainIgnore(path string, symlinks fileops.SymlinkPolicy) ([]IgnoreDecision, error) {
+	return app.explain(path, symlinks, fileops.Explain)
+}
+
+// ExplainIgnoreTree is like ExplainIgnore, but for a directory it also returns
+// the decisions for everything inside it that CopyFiles would walk.
+func (app *Application) ExplainIgnoreTree(dir string, symlinks fileops.SymlinkPolicy) ([]IgnoreDecision, error) {
+	return app.explain(dir, symlinks, fileops.ExplainTree)
+}
+
+// explain calls the explain function for the path and every source path it is in.
+func (app *Application) explain(path string, symlinks fileops.SymlinkPolicy, explain func(src, path string, opts fileops.Options) (fileops.Decision, error)) ([]IgnoreDecision, error) {
+	path = resolveParent(path)
+
+	paths, err := app.getEnabledPaths()
+	if err != nil {
+		return nil, err
+	}
+	patterns, err := app.GetIgnorePatterns()
+	if err != nil {
+		return nil, err
+	}
+
+	var decisions []IgnoreDecision
+	for _, src := range paths {
+		if !isWithin(path, src.Path) {
+			continue
+		}
+		opts := fileops.Options{Symlinks: symlinks, Exclude: app.excludedDirs()}
+		opts.Ignore, err = fileops.NewMatcher(sourcePatterns(patterns, src))
+		if err != nil {
+			return nil, err
+		}
+		if src.Symlinks != "" {
+			opts.Symlinks, err = fileops.ParseSymlinkPolicy(src.Symlinks)
+			if err != nil {
+				return nil, fmt.Errorf("path %s: %v", src.Path, err)
+			}
+		}
+		decision, err := explain(src.Path, path, opts)
+		if err != nil {
+			return nil, err
+		}
+		decisions = append(decisions, IgnoreDecision{Source: src.Path, Decision: decision})
+	}
+	return decisions, nil
+}
@@ -0,0 +1,70 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/chtozamm/dotfiles-collector/internal/database"
+	"github.com/chtozamm/dotfiles-collector/internal/fileops"
+)
+
+// ImportResult reports what importing ignore patterns from a file has done.
+type ImportResult struct {
+	Added      []string // Patterns added to the collector, in the order of the file
+	Duplicates []string // Patterns left out because they are already added
+	Invalid    []error  // Lines left out because they aren't valid patterns
+}
+
+// ImportIgnorePatterns adds the patterns of a file, one per line, such as
+// a .gitignore with globs or a list of regular expressions. Blank lines and
+// comments are skipped. If source is not empty, the patterns apply only to
+// that source path.
+//
+// Duplicates and invalid lines are reported in the result rather than
+// stopping the import. The patterns are added in a single transaction,
+// so if storing any of them fails, none of them are added.
+func (app *Application) ImportIgnorePatterns(file string, kind fileops.PatternKind, source string) (*ImportResult, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("read file: %v", err)
+	}
+
+	sourceID, err := app.sourceID(source)
+	if err != nil {
+		return nil, err
+	}
+
+	patterns, invalid := fileops.ParsePatterns(data, kind, file)
+	result := &ImportResult{Invalid: invalid}
+
+	tx, err := app.Conn.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+	db := app.DB.WithTx(tx)
+
+	for _, pattern := range patterns {
+		// Patterns repeated in the file are found as well, since they are added in the same transaction
+		params := database.GetIgnorePatternParams{Pattern: pattern.Pattern, Kind: kind.String(), SourceID: sourceID}
+		if _, err := db.GetIgnorePattern(context.Background(), params); err == nil {
+			result.Duplicates = append(result.Duplicates, pattern.Pattern)
+			continue
+		}
+		err := db.AddIgnorePattern(context.Background(), database.AddIgnorePatternParams{
+			Pattern:  pattern.Pattern,
+			Kind:     kind.String(),
+			SourceID: sourceID,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("add pattern %s: %v", pattern.Pattern, err)
+		}
+		result.Added = append(result.Added, pattern.Pattern)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("commit transaction: %v", err)
+	}
+	return result, nil
+}
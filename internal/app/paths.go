@@ -0,0 +1,156 @@
+package app
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/chtozamm/dotfiles-collector/internal/fileops"
+)
+
+// resolvePath returns the absolute path with symlinks resolved,
+// or only the absolute path if it can't be resolved.
+func resolvePath(path string) string {
+	if resolved, err := filepath.EvalSymlinks(path); err == nil {
+		return resolved
+	}
+	if abs, err := filepath.Abs(path); err == nil {
+		return abs
+	}
+	return filepath.Clean(path)
+}
+
+// isWithin reports whether the path is the directory itself or is inside it.
+func isWithin(path, dir string) bool {
+	rel, err := filepath.Rel(dir, path)
+	if err != nil {
+		return false
+	}
+	return rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) && !filepath.IsAbs(rel)
+}
+
+// target returns the path in the destination the source is collected to.
+func (app *Application) target(path, subdir string) string {
+	return filepath.Join(app.Destination, subdir, filepath.Base(path))
+}
+
+// checkCollision returns an error if the source is collected to the same path
+// as one of the other sources, or to a path inside or around the path of another
+// source, where collecting one of them would overwrite or prune files of the other.
+// Paths are compared case-insensitively.
+func (app *Application) checkCollision(path, subdir string, sources []SourcePath) error {
+	target := app.target(path, subdir)
+	for _, other := range sources {
+		if other.Path == path {
+			continue
+		}
+		otherTarget := app.target(other.Path, other.Subdir)
+		var conflict string
+		switch {
+		case strings.EqualFold(target, otherTarget):
+			conflict = "which is already used by " + other.Path
+		case isWithin(strings.ToLower(target), strings.ToLower(otherTarget)):
+			conflict = "which is inside " + otherTarget + ", where " + other.Path + " is collected"
+		case isWithin(strings.ToLower(otherTarget), strings.ToLower(target)):
+			conflict = "which contains " + otherTarget + ", where " + other.Path + " is collected"
+		default:
+			continue
+		}
+		return fmt.Errorf(
+			"source %s would be collected to %s, %s, try adding it with a subdir, e.g. %q",
+			path, target, conflict, suggestSubdir(path),
+		)
+	}
+	return nil
+}
+
+// suggestSubdir returns a subdir that tells the source apart
+// from other sources with the same name.
+func suggestSubdir(path string) string {
+	parent := filepath.Base(filepath.Dir(path))
+	if trimmed := strings.TrimLeft(parent, "."); trimmed != "" {
+		return trimmed
+	}
+	return parent
+}
+
+// sourceID returns the ID of the source path, or zero if the path is empty.
+func (app *Application) sourceID(path string) (int64, error) {
+	if path == "" {
+		return 0, nil
+	}
+	source, err := app.getCollectPath(path)
+	if err != nil {
+		return 0, fmt.Errorf("path %s is not added to the collector", path)
+	}
+	return source.ID, nil
+}
+
+// getCollectPath returns the source path with the given name. Names are
+// compared with the paths as they are expanded on this machine.
+func (app *Application) getCollectPath(name string) (SourcePath, error) {
+	paths, err := app.GetCollectPaths()
+	if err != nil {
+		return SourcePath{}, err
+	}
+	// Once linked, a source resolves to its collected copy, so it is looked up by its absolute path as well
+	abs, _ := filepath.Abs(name)
+	for _, path := range paths {
+		if path.Path == resolvePath(name) || path.Path == abs {
+			return path, nil
+		}
+	}
+	return SourcePath{}, fmt.Errorf("path %s does not exist", name)
+}
+
+// sourcePatterns returns the enabled global patterns together with the enabled
+// patterns scoped to the source, in the order they were added.
+func sourcePatterns(patterns []IgnorePattern, src SourcePath) []fileops.IgnorePattern {
+	var sourcePatterns []fileops.IgnorePattern
+	for _, pattern := range patterns {
+		if !pattern.Enabled {
+			continue
+		}
+		switch pattern.SourceID {
+		case 0:
+			source := "global"
+			if pattern.Preset != "" {
+				source = "preset " + pattern.Preset
+			}
+			sourcePatterns = append(sourcePatterns, fileops.IgnorePattern{Pattern: pattern.Pattern, Kind: pattern.Kind, Source: source})
+		case src.ID:
+			sourcePatterns = append(sourcePatterns, fileops.IgnorePattern{Pattern: pattern.Pattern, Kind: pattern.Kind, Source: "path " + src.Path})
+		}
+	}
+	return sourcePatterns
+}
+
+// resolveParent returns the absolute path with symlinks resolved in its parent
+// directory, so that a link inside a source path is still found in it.
+func resolveParent(path string) string {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return filepath.Clean(path)
+	}
+	return filepath.Join(resolvePath(filepath.Dir(abs)), filepath.Base(abs))
+}
+
+// excludedDirs returns the directories of the application that must never be collected.
+func (app *Application) excludedDirs() []string {
+	return []string{resolvePath(app.Destination), resolvePath(app.DataDir)}
+}
+
+// checkSource returns an error if the source is inside the destination
+// or the data directory, and warnings if either of them is inside the source.
+func (app *Application) checkSource(path string) ([]string, error) {
+	var warnings []string
+	for _, dir := range app.excludedDirs() {
+		if isWithin(path, dir) {
+			return nil, fmt.Errorf("source %s overlaps with %s, which holds the collector's own files", path, dir)
+		}
+		if isWithin(dir, path) {
+			warnings = append(warnings, fmt.Sprintf("%s is inside source %s and is left out of the collection", dir, path))
+		}
+	}
+	return warnings, nil
+}
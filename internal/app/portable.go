@@ -0,0 +1,123 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+
+	"github.com/chtozamm/dotfiles-collector/internal/database"
+)
+
+// portableVars are the variables source paths are stored relative to when
+// they are set and contain the path, besides the home directory.
+var portableVars = []string{"XDG_CONFIG_HOME", "XDG_DATA_HOME", "APPDATA", "LOCALAPPDATA"}
+
+// defaultVars are the values the XDG variables have when they aren't set,
+// relative to the home directory.
+var defaultVars = map[string]string{
+	"XDG_CONFIG_HOME": ".config",
+	"XDG_DATA_HOME":   filepath.Join(".local", "share"),
+}
+
+// portablePath returns the resolved path in a form that can be used on other
+// machines: relative to the home directory, e.g. "~/.bashrc", or to one of the
+// variables, e.g. "$XDG_CONFIG_HOME/nvim" or "%APPDATA%/Code/User". Variables
+// given in vars are tried first, then portableVars and the home directory,
+// and the one closest to the path is used. An empty string is returned
+// if the path is inside none of them.
+func portablePath(path string, vars []string) string {
+	placeholder, base := "", ""
+	consider := func(name, value string) {
+		if value == "" {
+			return
+		}
+		value = resolvePath(value)
+		if isWithin(path, value) && len(value) > len(base) {
+			placeholder, base = name, value
+		}
+	}
+	for _, name := range slices.Concat(vars, portableVars) {
+		if runtime.GOOS == "windows" {
+			consider("%"+name+"%", os.Getenv(name))
+		} else {
+			consider("$"+name, os.Getenv(name))
+		}
+	}
+	if home, err := os.UserHomeDir(); err == nil {
+		consider("~", home)
+	}
+
+	if placeholder == "" {
+		return ""
+	}
+	rel, err := filepath.Rel(base, path)
+	if err != nil {
+		return ""
+	}
+	if rel == "." {
+		return placeholder
+	}
+	return placeholder + "/" + filepath.ToSlash(rel)
+}
+
+// expandPortable returns the path the portable form stands for on this machine.
+// Variables are accepted both as $VAR and %VAR%, regardless of the system.
+// It reports false if the form is empty or its placeholder can't be expanded.
+func expandPortable(portable string) (string, bool) {
+	placeholder, rest, _ := strings.Cut(portable, "/")
+
+	var base string
+	switch {
+	case placeholder == "~":
+		base, _ = os.UserHomeDir()
+	case strings.HasPrefix(placeholder, "$"):
+		base = lookupVar(placeholder[1:])
+	case len(placeholder) > 2 && strings.HasPrefix(placeholder, "%") && strings.HasSuffix(placeholder, "%"):
+		base = lookupVar(placeholder[1 : len(placeholder)-1])
+	}
+	if base == "" {
+		return "", false
+	}
+	return filepath.Join(resolvePath(base), filepath.FromSlash(rest)), true
+}
+
+// lookupVar returns the value of the variable, or its default value
+// if it isn't set and has one.
+func lookupVar(name string) string {
+	if value := os.Getenv(name); value != "" {
+		return value
+	}
+	if value, ok := defaultVars[name]; ok {
+		if home, err := os.UserHomeDir(); err == nil {
+			return filepath.Join(home, value)
+		}
+	}
+	return ""
+}
+
+// fillPortablePaths stores the portable form of the source paths that were
+// added before it was stored along with the resolved path.
+func (app *Application) fillPortablePaths() error {
+	paths, err := app.DB.GetCollectPaths(context.Background())
+	if err != nil {
+		return fmt.Errorf("get collect paths: %v", err)
+	}
+	for _, path := range paths {
+		if path.Portable != "" {
+			continue
+		}
+		portable := portablePath(path.Path, nil)
+		if portable == "" {
+			continue
+		}
+		err := app.DB.SetCollectPathPortable(context.Background(), database.SetCollectPathPortableParams{Portable: portable, ID: path.ID})
+		if err != nil {
+			return fmt.Errorf("update path %s: %v", path.Path, err)
+		}
+	}
+	return nil
+}
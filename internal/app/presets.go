@@ -0,0 +1,154 @@
+package app
+
+import (
+	"context"
+	"embed"
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/chtozamm/dotfiles-collector/internal/fileops"
+)
+
+// presetFiles holds the bundled presets, one file of glob patterns per preset.
+// The first line of a file is a comment that describes the preset.
+//
+//go:embed presets/*.ignore
+var presetFiles embed.FS
+
+// Preset is a named group of glob ignore patterns bundled with the collector.
+type Preset struct {
+	Name        string
+	Description string
+	Patterns    []string
+	Added       bool // Whether the preset is added to the collector
+}
+
+// GetPresets returns the bundled presets sorted by their names.
+func (app *Application) GetPresets() ([]Preset, error) {
+	entries, err := presetFiles.ReadDir("presets")
+	if err != nil {
+		return nil, fmt.Errorf("read presets: %v", err)
+	}
+
+	names, err := app.DB.GetPresets(context.Background())
+	if err != nil {
+		return nil, fmt.Errorf("get added presets: %v", err)
+	}
+	added := make(map[string]bool)
+	for _, name := range names {
+		added[name] = true
+	}
+
+	presets := make([]Preset, 0, len(entries))
+	for _, entry := range entries {
+		data, err := presetFiles.ReadFile(path.Join("presets", entry.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("read preset %s: %v", entry.Name(), err)
+		}
+		preset := Preset{Name: strings.TrimSuffix(entry.Name(), ".ignore")}
+		preset.Added = added[preset.Name]
+		for _, line := range strings.Split(string(data), "\n") {
+			line = strings.TrimSpace(line)
+			if strings.HasPrefix(line, "#") {
+				if preset.Description == "" {
+					preset.Description = strings.TrimSpace(strings.TrimPrefix(line, "#"))
+				}
+				continue
+			}
+			if line != "" {
+				preset.Patterns = append(preset.Patterns, line)
+			}
+		}
+		presets = append(presets, preset)
+	}
+	return presets, nil
+}
+
+// getPreset returns the bundled preset with the given name.
+func (app *Application) getPreset(name string) (Preset, error) {
+	presets, err := app.GetPresets()
+	if err != nil {
+		return Preset{}, err
+	}
+	names := make([]string, 0, len(presets))
+	for _, preset := range presets {
+		if preset.Name == name {
+			return preset, nil
+		}
+		names = append(names, preset.Name)
+	}
+	return Preset{}, fmt.Errorf("unknown preset %s, expected one of: %s", name, strings.Join(names, ", "))
+}
+
+// AddPreset adds the preset and its patterns to the collector as global glob patterns
+// and returns how many of the patterns were added. Patterns that were already added on
+// their own are left as they are, so they stay when the preset is removed. The preset
+// is added in a single transaction, so either all of its patterns are added or none.
+func (app *Application) AddPreset(name string) (int, error) {
+	preset, err := app.getPreset(name)
+	if err != nil {
+		return 0, err
+	}
+	if preset.Added {
+		return 0, fmt.Errorf("preset %s already added", name)
+	}
+
+	tx, err := app.Conn.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+	db := app.DB.WithTx(tx)
+
+	if err := db.AddPreset(context.Background(), preset.Name); err != nil {
+		return 0, fmt.Errorf("add preset %s: %v", name, err)
+	}
+	added := 0
+	for _, pattern := range preset.Patterns {
+		err := addIgnorePattern(db, pattern, fileops.PatternGlob, 0, preset.Name)
+		if err != nil {
+			if errors.Is(err, errPatternExists) {
+				continue
+			}
+			return 0, err
+		}
+		added++
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("commit transaction: %v", err)
+	}
+	return added, nil
+}
+
+// RemovePreset removes the preset and all patterns added with it from the collector.
+func (app *Application) RemovePreset(name string) error {
+	preset, err := app.getPreset(name)
+	if err != nil {
+		return err
+	}
+	if !preset.Added {
+		return fmt.Errorf("preset %s is not added", name)
+	}
+
+	tx, err := app.Conn.Begin()
+	if err != nil {
+		return fmt.Errorf("begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+	db := app.DB.WithTx(tx)
+
+	if err := db.RemovePresetIgnorePatterns(context.Background(), name); err != nil {
+		return fmt.Errorf("remove preset %s: %v", name, err)
+	}
+	if err := db.RemovePreset(context.Background(), name); err != nil {
+		return fmt.Errorf("remove preset %s: %v", name, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit transaction: %v", err)
+	}
+	return nil
+}
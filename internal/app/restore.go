@@ -0,0 +1,153 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/chtozamm/dotfiles-collector/internal/fileops"
+)
+
+// RestoreOptions configures a single run of restoring, linking or unlinking collected files.
+type RestoreOptions struct {
+	Paths           []string // Source paths to restore, all enabled paths if empty
+	Overwrite       bool     // Replace files that differ from the collected copies, or real files with links
+	Checksum        bool     // Detect unchanged files by their contents instead of size and modification time
+	Metadata        bool     // Preserve modes, times and extended attributes of restored files
+	ContinueOnError bool     // Restore everything possible and report all failures at the end
+}
+
+// RestoreFiles copies collected files from the destination back to the
+// source paths they were collected from and returns the summary of the run.
+// Files that differ from the collected copies are backed up before they are
+// replaced, see GetBackups.
+//
+// If ContinueOnError is set, the failures of all sources are returned
+// as fileops.Errors together with the result.
+func (app *Application) RestoreFiles(opts RestoreOptions) (*CollectResult, error) {
+	return app.deploy(opts, "restore", fileops.Copy)
+}
+
+// PlanRestoreFiles returns the summary of changes RestoreFiles would make
+// to the source paths without touching the disk.
+func (app *Application) PlanRestoreFiles(opts RestoreOptions) (*CollectResult, error) {
+	return app.deploy(opts, "restore", fileops.PlanCopy)
+}
+
+// LinkFiles replaces the files of the source paths with symbolic links to
+// their collected copies, so that the collection is the live configuration.
+// Existing files are replaced only if Overwrite is set, and backed up first.
+func (app *Application) LinkFiles(opts RestoreOptions) (*CollectResult, error) {
+	return app.deploy(opts, "link", fileops.Link)
+}
+
+// PlanLinkFiles returns the summary of changes LinkFiles would make
+// to the source paths without touching the disk.
+func (app *Application) PlanLinkFiles(opts RestoreOptions) (*CollectResult, error) {
+	return app.deploy(opts, "link", fileops.PlanLink)
+}
+
+// UnlinkFiles replaces the links made by LinkFiles with copies of the collected files.
+func (app *Application) UnlinkFiles(opts RestoreOptions) (*CollectResult, error) {
+	return app.deploy(opts, "unlink", fileops.Unlink)
+}
+
+// PlanUnlinkFiles returns the summary of changes UnlinkFiles would make
+// to the source paths without touching the disk.
+func (app *Application) PlanUnlinkFiles(opts RestoreOptions) (*CollectResult, error) {
+	return app.deploy(opts, "unlink", fileops.PlanUnlink)
+}
+
+// deploy applies fn to the collected copy of every source path and the parent
+// directory of the source, so that the copy ends up at the source path.
+// The action names what fn does in errors.
+func (app *Application) deploy(restoreOpts RestoreOptions, action string, fn func(src, dst string, opts fileops.Options) (*fileops.Plan, error)) (*CollectResult, error) {
+	start := time.Now()
+
+	paths, err := app.restorePaths(restoreOpts.Paths)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(paths) == 0 {
+		return nil, ErrNoPaths
+	}
+
+	// Links are restored as they were collected, and files that are replaced are backed up
+	opts := fileops.Options{
+		Overwrite:       restoreOpts.Overwrite,
+		CreateDst:       true,
+		Checksum:        restoreOpts.Checksum,
+		Symlinks:        fileops.SymlinkPreserve,
+		Metadata:        restoreOpts.Metadata,
+		ContinueOnError: restoreOpts.ContinueOnError,
+		Backup:          app.newBackupDir(),
+	}
+	result := &CollectResult{Plan: &fileops.Plan{}}
+	var errs fileops.Errors
+	for _, src := range paths {
+		collected := app.target(src.Path, src.Subdir)
+		if _, err := os.Lstat(collected); err != nil {
+			err = fmt.Errorf("path %s has not been collected to %s", src.Path, collected)
+			if !restoreOpts.ContinueOnError {
+				return nil, err
+			}
+			errs = append(errs, fileops.NewFileError(src.Path, err))
+			result.Sources = append(result.Sources, SourceResult{Path: src.Path, Failed: 1})
+			continue
+		}
+
+		// The collected copy has the name of the source, so it goes
+		// into the parent directory of the source
+		srcPlan, err := fn(collected, filepath.Dir(src.Path), opts)
+		result.Plan.Merge(srcPlan)
+		srcResult := newSourceResult(src.Path, srcPlan)
+		if err != nil {
+			if !restoreOpts.ContinueOnError {
+				return nil, fmt.Errorf("%s %s: %v", action, src.Path, err)
+			}
+			var srcErrs fileops.Errors
+			if errors.As(err, &srcErrs) {
+				errs = append(errs, srcErrs...)
+			} else {
+				errs = append(errs, fileops.NewFileError(src.Path, err))
+				srcResult.Failed++
+			}
+		}
+		result.Sources = append(result.Sources, srcResult)
+	}
+
+	result.Warnings = append(result.Warnings, result.Plan.Warnings...)
+	result.Backup = existingDir(opts.Backup)
+	result.Duration = time.Since(start)
+
+	if len(errs) > 0 {
+		return result, errs
+	}
+	return result, nil
+}
+
+// restorePaths returns the source paths with the given names, or all
+// enabled paths if no names are given. Disabled paths are restored
+// only when they are named.
+func (app *Application) restorePaths(names []string) ([]SourcePath, error) {
+	if len(names) == 0 {
+		paths, err := app.getEnabledPaths()
+		if err != nil {
+			return nil, fmt.Errorf("get paths: %v", err)
+		}
+		return paths, nil
+	}
+
+	restored := make([]SourcePath, 0, len(names))
+	for _, name := range names {
+		path, err := app.getCollectPath(name)
+		if err != nil {
+			return nil, err
+		}
+		restored = append(restored, path)
+	}
+	return restored, nil
+}
@@ -0,0 +1,79 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/chtozamm/dotfiles-collector/internal/fileops"
+)
+
+// ErrNoPaths is returned when there are no source paths to collect files from.
+var ErrNoPaths = errors.New("no paths found in database")
+
+// SourceResult summarizes what a collection run has done with a single source path.
+type SourceResult struct {
+	Path      string
+	Copied    int   // Files created or overwritten in the destination
+	Unchanged int   // Files skipped because the destination is up to date
+	Skipped   int   // Files and links left out, e.g. dangling symlinks
+	Ignored   int   // Files and directories matching ignore patterns
+	Deleted   int   // Files and directories removed in mirror mode
+	Failed    int   // Files that failed to copy
+	Bytes     int64 // Total size of copied files
+}
+
+// newSourceResult counts entries of the plan made for the source path.
+func newSourceResult(path string, plan *fileops.Plan) SourceResult {
+	result := SourceResult{Path: path}
+	if plan == nil {
+		return result
+	}
+	result.Copied = plan.Count(fileops.ActionCreate) + plan.Count(fileops.ActionOverwrite)
+	result.Unchanged = plan.Count(fileops.ActionUnchanged)
+	result.Skipped = plan.Count(fileops.ActionSkip)
+	result.Ignored = plan.Count(fileops.ActionIgnore)
+	result.Deleted = plan.Count(fileops.ActionDelete)
+	result.Failed = len(plan.Errors())
+	result.Bytes = plan.Bytes()
+	return result
+}
+
+// CollectResult summarizes a collection run. In dry-run mode,
+// it describes what the run would do.
+type CollectResult struct {
+	Sources  []SourceResult
+	Plan     *fileops.Plan // All entries of the run, in the order of sources
+	Duration time.Duration
+	Warnings []string
+	Backup   string // Directory with the files replaced by the run, empty if none were replaced
+}
+
+// Total returns the sum of the results of all sources.
+func (r *CollectResult) Total() SourceResult {
+	total := SourceResult{}
+	for _, src := range r.Sources {
+		total.Copied += src.Copied
+		total.Unchanged += src.Unchanged
+		total.Skipped += src.Skipped
+		total.Ignored += src.Ignored
+		total.Deleted += src.Deleted
+		total.Failed += src.Failed
+		total.Bytes += src.Bytes
+	}
+	return total
+}
+
+// FormatSize returns the size in bytes in a human-readable form, e.g. "1.5 MB".
+func FormatSize(bytes int64) string {
+	const unit = 1024
+	if bytes < unit {
+		return fmt.Sprintf("%d B", bytes)
+	}
+	div, exp := int64(unit), 0
+	for n := bytes / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %cB", float64(bytes)/float64(div), "KMGTPE"[exp])
+}
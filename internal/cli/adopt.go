@@ -0,0 +1,43 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/chtozamm/dotfiles-collector/internal/app"
+	"github.com/spf13/cobra"
+)
+
+func setupAdoptCmd(app *app.Application, rootCmd *cobra.Command) {
+	adoptCmd := &cobra.Command{
+		Use:   "adopt <path> [parent]",
+		Short: "Move a file into the collection and link it back",
+		Long: `Move a file into the collection and link it back.
+
+The path is added to the collector, moved into the destination and replaced
+with a link to the moved file, so that the collection is the live file from
+now on. A directory is moved as a whole and its files are linked back one by
+one, like with link. If any step fails, the previous ones are undone.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			if len(args) == 0 {
+				fmt.Println(`Usage:
+  dotfiles-collector adopt <path> [parent]`)
+				return
+			}
+			var parentDir string
+			if len(args) == 2 {
+				parentDir = args[1]
+			}
+			warnings, err := app.AdoptPath(args[0], parentDir)
+			if err != nil {
+				fmt.Printf("Failed to adopt path: %s\n", err)
+				os.Exit(1)
+			}
+			for _, warning := range warnings {
+				fmt.Printf("Warning: %s\n", warning)
+			}
+		},
+	}
+
+	rootCmd.AddCommand(adoptCmd)
+}
@@ -0,0 +1,111 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/chtozamm/dotfiles-collector/internal/app"
+	"github.com/chtozamm/dotfiles-collector/internal/fileops"
+	"github.com/spf13/cobra"
+)
+
+func setupBackupsCmd(app *app.Application, rootCmd *cobra.Command) {
+	var (
+		keep      int
+		olderThan string
+	)
+
+	backupsCmd := &cobra.Command{
+		Use:   "backups <list|restore|prune>",
+		Short: "Manage backups of replaced files",
+		Long:  "List, restore or prune the files saved before restore or link replaced them.",
+	}
+
+	listBackups := &cobra.Command{
+		Use:   "list",
+		Short: "List backups",
+		Long:  "List backups with the files saved in them, the newest first.",
+		Run: func(cmd *cobra.Command, args []string) {
+			var sb strings.Builder
+			backups, err := app.GetBackups()
+			if err != nil {
+				fmt.Printf("Failed to get backups: %s\n", err)
+				return
+			}
+			for _, backup := range backups {
+				sb.WriteString(fmt.Sprintf("%s, made %s\n", backup.ID, backup.Time.Format(time.DateTime)))
+				for _, file := range backup.Files {
+					sb.WriteString("  " + file + "\n")
+				}
+			}
+			fmt.Print(sb.String())
+		},
+	}
+
+	restoreBackup := &cobra.Command{
+		Use:   "restore <backup> [path...]",
+		Short: "Restore files from a backup",
+		Long: `Restore files from a backup to their original paths.
+
+Without paths, every file of the backup is restored, otherwise only the given ones.
+The files that are replaced are backed up in turn.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			if len(args) == 0 {
+				fmt.Println(`Usage:
+  dotfiles-collector backups restore <backup> [path...]`)
+				return
+			}
+			result, err := app.RestoreBackup(args[0], args[1:])
+			if err != nil {
+				fmt.Printf("Failed to restore backup: %s\n", err)
+				os.Exit(1)
+			}
+			fmt.Println("Successfully restored the backup.")
+			fmt.Print(formatResult(result))
+		},
+	}
+
+	pruneBackups := &cobra.Command{
+		Use:   "prune",
+		Short: "Remove old backups",
+		Long: `Remove old backups.
+
+With --keep, all but the given number of the newest backups are removed, with
+--older-than, backups older than the given age, such as 30d. If both are given,
+a backup is removed if either of them applies.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			var age time.Duration
+			if olderThan != "" {
+				var err error
+				if age, err = fileops.ParseAge(olderThan); err != nil {
+					fmt.Printf("Failed to prune backups: %s\n", err)
+					os.Exit(1)
+				}
+			}
+			if keep <= 0 && age == 0 {
+				fmt.Println(`Usage:
+  dotfiles-collector backups prune --keep <number>
+  dotfiles-collector backups prune --older-than <age>`)
+				return
+			}
+			removed, err := app.PruneBackups(keep, age)
+			for _, id := range removed {
+				fmt.Printf("Removed backup %s\n", id)
+			}
+			if err != nil {
+				fmt.Printf("Failed to prune backups: %s\n", err)
+				os.Exit(1)
+			}
+		},
+	}
+
+	pruneBackups.Flags().IntVar(&keep, "keep", 0, "number of the newest backups to keep")
+	pruneBackups.Flags().StringVar(&olderThan, "older-than", "", "remove backups older than the given age, such as 30d")
+
+	rootCmd.AddCommand(backupsCmd)
+	backupsCmd.AddCommand(listBackups)
+	backupsCmd.AddCommand(restoreBackup)
+	backupsCmd.AddCommand(pruneBackups)
+}
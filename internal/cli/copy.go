@@ -1,26 +1,170 @@
 package cli
 
 import (
+	"errors"
 	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
 
+	"github.com/charmbracelet/lipgloss/table"
 	"github.com/chtozamm/dotfiles-collector/internal/app"
+	"github.com/chtozamm/dotfiles-collector/internal/fileops"
 	"github.com/spf13/cobra"
 )
 
+// Flags of the collect command
+var (
+	collectDryRun   bool
+	collectSymlinks string
+	collectOpts     app.CollectOptions
+)
+
 func setupCollectCmd(app *app.Application, rootCmd *cobra.Command) {
 	collectCmd := &cobra.Command{
 		Use:   "collect",
 		Short: "Collect files specified in source paths",
 		Long:  "Collect files specified in source paths.",
 		Run: func(cmd *cobra.Command, args []string) {
-			err := app.CopyFiles()
+			policy, err := fileops.ParseSymlinkPolicy(collectSymlinks)
 			if err != nil {
 				fmt.Printf("Failed to collect files: %v\n", err)
+				os.Exit(1)
+			}
+			collectOpts.Symlinks = policy
+
+			if collectOpts.Jobs < 1 {
+				fmt.Println("Failed to collect files: number of jobs must be at least 1")
+				os.Exit(1)
+			}
+
+			if collectDryRun {
+				result, err := app.PlanCopyFiles(collectOpts)
+				var errs fileops.Errors
+				if err != nil && !errors.As(err, &errs) {
+					fmt.Printf("Failed to plan collection: %v\n", err)
+					os.Exit(1)
+				}
+				fmt.Print(formatPlan(result.Plan))
+				fmt.Println("\nDry run, nothing has been changed.")
+				fmt.Print(formatResult(result))
+				if len(errs) > 0 {
+					fmt.Printf("\n%d files can't be collected:\n%s\n", len(errs), formatErrors(errs))
+					os.Exit(1)
+				}
 				return
 			}
-			fmt.Println("Successfully collected the files.")
+
+			result, err := app.CopyFiles(collectOpts)
+			var errs fileops.Errors
+			if err != nil && !errors.As(err, &errs) {
+				fmt.Printf("Failed to collect files: %v\n", err)
+				os.Exit(1)
+			}
+
+			if len(errs) > 0 {
+				fmt.Println("Collected the files with errors.")
+			} else {
+				fmt.Println("Successfully collected the files.")
+			}
+			fmt.Print(formatResult(result))
+			if len(errs) > 0 {
+				fmt.Printf("\n%d files failed to copy:\n%s\n", len(errs), formatErrors(errs))
+				os.Exit(1)
+			}
 		},
 	}
 
+	collectCmd.Flags().BoolVar(&collectDryRun, "dry-run", false, "show what would be collected without copying anything")
+	collectCmd.Flags().StringVar(&collectSymlinks, "symlinks", "follow", "how to treat symlinks in paths that don't set their own policy: follow, preserve or skip")
+	collectCmd.Flags().BoolVar(&collectOpts.Metadata, "preserve", false, "preserve modes, times and extended attributes of collected files")
+	collectCmd.Flags().BoolVar(&collectOpts.Mirror, "mirror", false, "remove collected files that no longer exist in the sources or are ignored")
+	collectCmd.Flags().IntVarP(&collectOpts.Jobs, "jobs", "j", 1, "number of files to copy in parallel")
+	collectCmd.Flags().BoolVarP(&collectOpts.ContinueOnError, "continue-on-error", "k", false, "keep collecting when a file fails to copy and report all failures at the end")
+	collectCmd.Flags().BoolVar(&collectOpts.Checksum, "checksum", false, "detect unchanged files by their contents instead of size and modification time")
+
 	rootCmd.AddCommand(collectCmd)
 }
+
+// formatPlan returns a human-readable listing of the plan followed by a summary.
+func formatPlan(plan *fileops.Plan) string {
+	var sb strings.Builder
+	for _, entry := range plan.Entries {
+		if entry.Action == fileops.ActionUnchanged {
+			continue
+		}
+		action := entry.Action.String()
+		if entry.Err != nil {
+			action = "failed"
+		}
+		path := entry.Src
+		if entry.Action == fileops.ActionDelete {
+			path = entry.Dst
+		}
+		if entry.IsDir {
+			path += "/"
+		}
+		if entry.Reason != "" {
+			path += " (" + entry.Reason + ")"
+		}
+		if entry.Action == fileops.ActionIgnore || entry.Action == fileops.ActionSkip || entry.Action == fileops.ActionDelete {
+			sb.WriteString(fmt.Sprintf("%-9s %s\n", action, path))
+			continue
+		}
+		if entry.Link != "" {
+			sb.WriteString(fmt.Sprintf("%-9s %s -> %s (symlink to %s)\n", action, path, entry.Dst, entry.Link))
+			continue
+		}
+		sb.WriteString(fmt.Sprintf("%-9s %s -> %s\n", action, path, entry.Dst))
+	}
+	return sb.String()
+}
+
+// formatResult returns a table with the results of every source,
+// followed by the totals, the backup and warnings of the run.
+func formatResult(result *app.CollectResult) string {
+	var sb strings.Builder
+
+	t := table.New().Headers("Source", "Copied", "Unchanged", "Skipped", "Ignored", "Deleted", "Failed", "Size")
+	for _, src := range result.Sources {
+		t.Row(
+			src.Path,
+			strconv.Itoa(src.Copied),
+			strconv.Itoa(src.Unchanged),
+			strconv.Itoa(src.Skipped),
+			strconv.Itoa(src.Ignored),
+			strconv.Itoa(src.Deleted),
+			strconv.Itoa(src.Failed),
+			app.FormatSize(src.Bytes),
+		)
+	}
+	sb.WriteString(t.String() + "\n")
+
+	total := result.Total()
+	sb.WriteString(fmt.Sprintf(
+		"%d copied (%s), %d unchanged, %d skipped, %d ignored, %d deleted, %d failed in %s\n",
+		total.Copied, app.FormatSize(total.Bytes), total.Unchanged, total.Skipped,
+		total.Ignored, total.Deleted, total.Failed, result.Duration.Round(time.Millisecond),
+	))
+	if result.Backup != "" {
+		sb.WriteString("Replaced files are backed up to " + result.Backup + "\n")
+	}
+
+	if len(result.Warnings) > 0 {
+		sb.WriteString("\nWarnings:\n")
+		for _, warning := range result.Warnings {
+			sb.WriteString("  - " + warning + "\n")
+		}
+	}
+	return sb.String()
+}
+
+// formatErrors returns a table of failed files with the kinds of their failures.
+func formatErrors(errs fileops.Errors) string {
+	t := table.New().Headers("Kind", "Path", "Error")
+	for _, err := range errs {
+		t.Row(err.Kind.String(), err.Path, err.Err.Error())
+	}
+	return t.String()
+}
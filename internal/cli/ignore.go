@@ -3,30 +3,72 @@ package cli
 import (
 	"fmt"
 	"os"
+	"path/filepath"
 	"strings"
 
+	"github.com/charmbracelet/lipgloss/tree"
+
 	"github.com/chtozamm/dotfiles-collector/internal/app"
+	"github.com/chtozamm/dotfiles-collector/internal/fileops"
 	"github.com/spf13/cobra"
 )
 
 func setupIgnoreCmd(app *app.Application, rootCmd *cobra.Command) {
+	var (
+		glob     bool
+		sizeRule bool
+		ageRule  bool
+		typeRule bool
+		source   string
+		asTree   bool
+		symlinks string
+	)
+
+	// patternKind returns the kind of the pattern chosen with the flags
+	patternKind := func() fileops.PatternKind {
+		switch {
+		case glob:
+			return fileops.PatternGlob
+		case sizeRule:
+			return fileops.PatternSize
+		case ageRule:
+			return fileops.PatternAge
+		case typeRule:
+			return fileops.PatternType
+		}
+		return fileops.PatternRegex
+	}
+
 	ignoreCmd := &cobra.Command{
-		Use:   "ignore <add|list|remove>",
+		Use:   "ignore <add|list|remove|enable|disable|import|test|preset>",
 		Short: "Manage ignore patterns",
-		Long:  "List, add or remove ignore patterns as regular expressions for the collector to ignore if encountered.",
+		Long:  "List, add, remove, enable or disable ignore patterns as regular expressions or gitignore-style globs for the collector to ignore if encountered.",
 	}
 
 	addPattern := &cobra.Command{
 		Use:   "add <pattern>",
 		Short: "Add ignore pattern",
-		Long:  `Add ignore pattern.`,
+		Long: `Add ignore pattern.
+
+By default, the pattern is a regular expression matched against the absolute path.
+With --glob, it follows the rules of .gitignore and is matched against the path
+relative to the source directory: "**" matches any number of
+directories, a leading "/" anchors the pattern, a trailing "/" matches only
+directories and a leading "!" includes paths ignored by earlier patterns again.
+
+Instead of a path pattern, a rule on file attributes can be added: with --size,
+files larger than the size (such as 5MB) are ignored, with --age, files not
+modified for longer than the age (such as 30d or 2y), and with --type, files of
+the type: socket, fifo, device or binary for compiled executables.
+
+With --path, the pattern applies only to the given source path.`,
 		Run: func(cmd *cobra.Command, args []string) {
 			if len(args) == 0 {
 				fmt.Println(`Usage:
   dotfiles-collector ignore add <pattern>`)
 				return
 			}
-			err := app.AddIgnorePattern(args[0])
+			err := app.AddIgnorePattern(args[0], patternKind(), source)
 			if err != nil {
 				fmt.Printf("Failed to add ignore pattern: %s\n", err)
 				os.Exit(1)
@@ -37,14 +79,17 @@ func setupIgnoreCmd(app *app.Application, rootCmd *cobra.Command) {
 	removePattern := &cobra.Command{
 		Use:   "remove <pattern>",
 		Short: "Remove ignore pattern",
-		Long:  `Remove ignore pattern.`,
+		Long: `Remove ignore pattern.
+
+The pattern is taken as a regular expression, unless a kind is given
+with the same flag it was added with, such as --glob.`,
 		Run: func(cmd *cobra.Command, args []string) {
 			if len(args) == 0 {
 				fmt.Println(`Usage:
   dotfiles-collector ignore remove <pattern>`)
 				return
 			}
-			err := app.RemoveIgnorePattern(args[0])
+			err := app.RemoveIgnorePattern(args[0], patternKind(), source)
 			if err != nil {
 				fmt.Printf("Failed to remove pattern: %s\n", err)
 				return
@@ -52,6 +97,46 @@ func setupIgnoreCmd(app *app.Application, rootCmd *cobra.Command) {
 		},
 	}
 
+	enablePattern := &cobra.Command{
+		Use:   "enable <pattern>",
+		Short: "Enable disabled ignore pattern",
+		Long: `Enable disabled ignore pattern, so that it ignores paths again.
+
+The pattern is taken as a regular expression, unless a kind is given
+with the same flag it was added with, such as --glob.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			if len(args) == 0 {
+				fmt.Println(`Usage:
+  dotfiles-collector ignore enable <pattern>`)
+				return
+			}
+			if err := app.SetIgnorePatternEnabled(args[0], patternKind(), source, true); err != nil {
+				fmt.Printf("Failed to enable pattern: %s\n", err)
+				os.Exit(1)
+			}
+		},
+	}
+
+	disablePattern := &cobra.Command{
+		Use:   "disable <pattern>",
+		Short: "Disable ignore pattern without removing it",
+		Long: `Disable ignore pattern without removing it. Disabled patterns are kept, but don't ignore anything.
+
+The pattern is taken as a regular expression, unless a kind is given
+with the same flag it was added with, such as --glob.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			if len(args) == 0 {
+				fmt.Println(`Usage:
+  dotfiles-collector ignore disable <pattern>`)
+				return
+			}
+			if err := app.SetIgnorePatternEnabled(args[0], patternKind(), source, false); err != nil {
+				fmt.Printf("Failed to disable pattern: %s\n", err)
+				os.Exit(1)
+			}
+		},
+	}
+
 	listIgnorePatterns := &cobra.Command{
 		Use:   "list",
 		Short: "List ignore patterns",
@@ -63,15 +148,296 @@ func setupIgnoreCmd(app *app.Application, rootCmd *cobra.Command) {
 				fmt.Printf("Failed to get ignore patterns: %s\n", err)
 				return
 			}
+			paths, err := app.GetCollectPaths()
+			if err != nil {
+				fmt.Printf("Failed to get paths: %s\n", err)
+				return
+			}
+			sources := make(map[int64]string, len(paths))
+			for _, path := range paths {
+				sources[path.ID] = path.Path
+			}
 			for _, pattern := range patterns {
-				sb.WriteString(pattern + "\n")
+				sb.WriteString(pattern.Pattern)
+				if pattern.Kind != fileops.PatternRegex {
+					sb.WriteString(", kind: " + pattern.Kind.String())
+				}
+				if pattern.SourceID != 0 {
+					sb.WriteString(", path: " + sources[pattern.SourceID])
+				}
+				if pattern.Preset != "" {
+					sb.WriteString(", preset: " + pattern.Preset)
+				}
+				if !pattern.Enabled {
+					sb.WriteString(", disabled")
+				}
+				sb.WriteString("\n")
+			}
+			fmt.Print(sb.String())
+		},
+	}
+
+	importPatterns := &cobra.Command{
+		Use:   "import <file>",
+		Short: "Import ignore patterns from a file",
+		Long: `Import ignore patterns from a file with one pattern per line.
+
+Files named .gitignore or .dotfilesignore and files with the .ignore extension
+are read as globs, other files as regular expressions unless --glob is given. Blank lines and
+lines starting with "#" are skipped. Patterns that are already added and invalid
+lines are reported, the rest of the patterns are added all together.
+
+With --path, the patterns apply only to the given source path.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			if len(args) == 0 {
+				fmt.Println(`Usage:
+  dotfiles-collector ignore import <file>`)
+				return
+			}
+			kind := fileops.PatternRegex
+			if glob || isGlobFile(args[0]) {
+				kind = fileops.PatternGlob
+			}
+			result, err := app.ImportIgnorePatterns(args[0], kind, source)
+			if err != nil {
+				fmt.Printf("Failed to import ignore patterns: %s\n", err)
+				os.Exit(1)
+			}
+			fmt.Printf("Imported %d %s patterns from %s\n", len(result.Added), kind, args[0])
+			for _, pattern := range result.Duplicates {
+				fmt.Printf("Skipped pattern %s, which is already added\n", pattern)
+			}
+			for _, err := range result.Invalid {
+				fmt.Printf("Skipped invalid pattern at %s\n", err)
+			}
+			if len(result.Invalid) > 0 {
+				os.Exit(1)
+			}
+		},
+	}
+
+	testPaths := &cobra.Command{
+		Use:   "test <path>...",
+		Short: "Explain whether paths are ignored",
+		Long: `Explain whether paths are ignored.
+
+For each path, report whether collecting would ignore it and which pattern
+decides it, including patterns from .dotfilesignore files. Paths that collecting
+skips for another reason, such as symlinks with --symlinks skip, are reported too.
+With --tree, the path must be a directory, which is shown with everything
+collecting would walk in it.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			if len(args) == 0 {
+				fmt.Println(`Usage:
+  dotfiles-collector ignore test <path>...
+  dotfiles-collector ignore test --tree <dir>`)
+				return
+			}
+			policy, err := fileops.ParseSymlinkPolicy(symlinks)
+			if err != nil {
+				fmt.Printf("Failed to test paths: %s\n", err)
+				os.Exit(1)
+			}
+			failed := false
+			for _, path := range args {
+				explain := app.ExplainIgnore
+				if asTree {
+					explain = app.ExplainIgnoreTree
+				}
+				decisions, err := explain(path, policy)
+				if err != nil {
+					fmt.Printf("Failed to test %s: %s\n", path, err)
+					failed = true
+					continue
+				}
+				if len(decisions) == 0 {
+					fmt.Printf("%s: not inside any source path\n", path)
+					continue
+				}
+				for _, decision := range decisions {
+					if asTree {
+						fmt.Println(decisionTree(decision.Decision))
+						continue
+					}
+					fmt.Printf("%s: %s", decision.Path, formatDecision(decision.Decision))
+					if len(decisions) > 1 {
+						fmt.Printf(" (source %s)", decision.Source)
+					}
+					fmt.Println()
+				}
+			}
+			if failed {
+				os.Exit(1)
+			}
+		},
+	}
+
+	presetCmd := &cobra.Command{
+		Use:   "preset <add|list|remove>",
+		Short: "Manage bundled ignore pattern presets",
+		Long:  "List, add or remove presets, which are named groups of glob ignore patterns bundled with the collector.",
+	}
+
+	addPreset := &cobra.Command{
+		Use:   "add <name>...",
+		Short: "Add patterns of presets",
+		Long:  `Add patterns of presets. Patterns that are already added are left as they are.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			if len(args) == 0 {
+				fmt.Println(`Usage:
+  dotfiles-collector ignore preset add <name>...`)
+				return
+			}
+			for _, name := range args {
+				added, err := app.AddPreset(name)
+				if err != nil {
+					fmt.Printf("Failed to add preset: %s\n", err)
+					os.Exit(1)
+				}
+				if added == 0 {
+					fmt.Printf("Added preset %s, all of its patterns were already added\n", name)
+					continue
+				}
+				fmt.Printf("Added %d patterns of preset %s\n", added, name)
+			}
+		},
+	}
+
+	removePreset := &cobra.Command{
+		Use:   "remove <name>...",
+		Short: "Remove patterns of presets",
+		Long:  `Remove all patterns that were added with presets.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			if len(args) == 0 {
+				fmt.Println(`Usage:
+  dotfiles-collector ignore preset remove <name>...`)
+				return
+			}
+			for _, name := range args {
+				if err := app.RemovePreset(name); err != nil {
+					fmt.Printf("Failed to remove preset: %s\n", err)
+					os.Exit(1)
+				}
+			}
+		},
+	}
+
+	listPresets := &cobra.Command{
+		Use:   "list",
+		Short: "List presets",
+		Long:  "List presets bundled with the collector and whether they are added.",
+		Run: func(cmd *cobra.Command, args []string) {
+			var sb strings.Builder
+			presets, err := app.GetPresets()
+			if err != nil {
+				fmt.Printf("Failed to get presets: %s\n", err)
+				return
+			}
+			for _, preset := range presets {
+				sb.WriteString(preset.Name)
+				if preset.Added {
+					sb.WriteString(" (added)")
+				}
+				sb.WriteString(": " + preset.Description + "\n")
+				sb.WriteString("  " + strings.Join(preset.Patterns, " ") + "\n")
 			}
 			fmt.Print(sb.String())
 		},
 	}
 
+	addPattern.Flags().BoolVar(&glob, "glob", false, "treat the pattern as a gitignore-style glob instead of a regular expression")
+	addPattern.Flags().BoolVar(&sizeRule, "size", false, "ignore files larger than the given size, such as 5MB")
+	addPattern.Flags().BoolVar(&ageRule, "age", false, "ignore files not modified for longer than the given age, such as 2y")
+	addPattern.Flags().BoolVar(&typeRule, "type", false, "ignore files of the given type: socket, fifo, device or binary")
+	addPattern.MarkFlagsMutuallyExclusive("glob", "size", "age", "type")
+	for _, cmd := range []*cobra.Command{removePattern, enablePattern, disablePattern} {
+		cmd.Flags().BoolVar(&glob, "glob", false, "the pattern is a gitignore-style glob")
+		cmd.Flags().BoolVar(&sizeRule, "size", false, "the pattern is a size rule")
+		cmd.Flags().BoolVar(&ageRule, "age", false, "the pattern is an age rule")
+		cmd.Flags().BoolVar(&typeRule, "type", false, "the pattern is a type rule")
+		cmd.MarkFlagsMutuallyExclusive("glob", "size", "age", "type")
+	}
+	addPattern.Flags().StringVar(&source, "path", "", "apply the pattern only to the given source path")
+	removePattern.Flags().StringVar(&source, "path", "", "remove the pattern from the given source path")
+	enablePattern.Flags().StringVar(&source, "path", "", "enable the pattern of the given source path")
+	disablePattern.Flags().StringVar(&source, "path", "", "disable the pattern of the given source path")
+	importPatterns.Flags().BoolVar(&glob, "glob", false, "read the patterns as gitignore-style globs instead of regular expressions")
+	importPatterns.Flags().StringVar(&source, "path", "", "apply the patterns only to the given source path")
+	testPaths.Flags().BoolVar(&asTree, "tree", false, "show the whole directory annotated with ignore decisions")
+	testPaths.Flags().StringVar(&symlinks, "symlinks", "follow", "how collecting treats symlinks in paths that don't set their own policy: follow, preserve or skip")
+
 	rootCmd.AddCommand(ignoreCmd)
 	ignoreCmd.AddCommand(addPattern)
 	ignoreCmd.AddCommand(removePattern)
 	ignoreCmd.AddCommand(listIgnorePatterns)
+	ignoreCmd.AddCommand(enablePattern)
+	ignoreCmd.AddCommand(disablePattern)
+	ignoreCmd.AddCommand(importPatterns)
+	ignoreCmd.AddCommand(testPaths)
+	ignoreCmd.AddCommand(presetCmd)
+	presetCmd.AddCommand(addPreset)
+	presetCmd.AddCommand(removePreset)
+	presetCmd.AddCommand(listPresets)
+}
+
+// formatPattern returns the kind, the text and the origin of the pattern.
+func formatPattern(pattern *fileops.IgnorePattern) string {
+	return fmt.Sprintf("%s %q from %s", pattern.Kind, pattern.Pattern, pattern.Source)
+}
+
+// formatDecision returns a sentence explaining the decision.
+func formatDecision(d fileops.Decision) string {
+	switch {
+	case d.Parent != "" && d.Reason != "":
+		return fmt.Sprintf("skipped with %s, which is skipped as %s", d.Parent, describeReason(d.Reason))
+	case d.Reason != "":
+		return "skipped as " + describeReason(d.Reason)
+	case d.Parent != "":
+		return fmt.Sprintf("ignored with %s, which matches %s", d.Parent, formatPattern(d.Pattern))
+	case d.Ignored:
+		return "ignored by " + formatPattern(d.Pattern)
+	case d.Pattern != nil:
+		return "collected, included again by " + formatPattern(d.Pattern)
+	}
+	return "collected"
+}
+
+// describeReason returns the reason a path is skipped for as a noun phrase.
+func describeReason(reason string) string {
+	if reason == "excluded" {
+		return "a directory of the collector"
+	}
+	return "a " + reason
+}
+
+// decisionTree returns a tree of the directory with the ignore decisions
+// for the paths matched by a pattern.
+func decisionTree(d fileops.Decision) *tree.Tree {
+	t := tree.Root(d.Path + decisionNote(d))
+	for _, child := range d.Children {
+		if !child.IsDir || child.Ignored || child.Reason != "" {
+			t.Child(filepath.Base(child.Path) + decisionNote(child))
+			continue
+		}
+		node := decisionTree(child)
+		node.Root(filepath.Base(child.Path) + decisionNote(child))
+		t.Child(node)
+	}
+	return t
+}
+
+// decisionNote returns the decision in brackets, or nothing for
+// paths that no pattern matches and that aren't skipped.
+func decisionNote(d fileops.Decision) string {
+	if d.Pattern == nil && d.Reason == "" {
+		return ""
+	}
+	return " [" + formatDecision(d) + "]"
+}
+
+// isGlobFile reports whether the ignore file has the name of a file with
+// gitignore-style globs.
+func isGlobFile(path string) bool {
+	name := filepath.Base(path)
+	return name == ".gitignore" || name == fileops.IgnoreFileName || filepath.Ext(name) == ".ignore"
 }
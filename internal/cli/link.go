@@ -0,0 +1,98 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/chtozamm/dotfiles-collector/internal/app"
+	"github.com/chtozamm/dotfiles-collector/internal/fileops"
+	"github.com/spf13/cobra"
+)
+
+// Flags of the link and unlink commands
+var (
+	linkDryRun bool
+	linkOpts   app.RestoreOptions
+)
+
+func setupLinkCmd(app *app.Application, rootCmd *cobra.Command) {
+	linkCmd := &cobra.Command{
+		Use:   "link [path...]",
+		Short: "Replace source files with links to the collected files",
+		Long: `Replace source files with links to the collected files.
+
+Every collected file is linked to from the path it was collected from, so that
+changes to the live configuration are made to the collection right away. Missing
+directories are created. Without arguments, all enabled source paths are linked,
+otherwise only the given ones. Existing files are skipped unless --force is given,
+directories are never replaced. Use unlink to turn the links back into files.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			linkOpts.Paths = args
+			deploy(app.LinkFiles, app.PlanLinkFiles, linkOpts, linkDryRun, "link", "linked")
+		},
+	}
+
+	unlinkCmd := &cobra.Command{
+		Use:   "unlink [path...]",
+		Short: "Replace links to the collected files with copies",
+		Long: `Replace links to the collected files with copies.
+
+Reverses link: every link that points to a collected file is replaced with
+a copy of the file. Other files are left as they are. Without arguments, all
+enabled source paths are unlinked, otherwise only the given ones.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			linkOpts.Paths = args
+			deploy(app.UnlinkFiles, app.PlanUnlinkFiles, linkOpts, linkDryRun, "unlink", "unlinked")
+		},
+	}
+
+	linkCmd.Flags().BoolVar(&linkDryRun, "dry-run", false, "show what would be linked without changing anything")
+	linkCmd.Flags().BoolVarP(&linkOpts.Overwrite, "force", "f", false, "replace existing files with links")
+	linkCmd.Flags().BoolVarP(&linkOpts.ContinueOnError, "continue-on-error", "k", false, "keep linking when a file fails and report all failures at the end")
+	unlinkCmd.Flags().BoolVar(&linkDryRun, "dry-run", false, "show what would be unlinked without changing anything")
+	unlinkCmd.Flags().BoolVarP(&linkOpts.ContinueOnError, "continue-on-error", "k", false, "keep unlinking when a file fails and report all failures at the end")
+
+	rootCmd.AddCommand(linkCmd)
+	rootCmd.AddCommand(unlinkCmd)
+}
+
+// deploy runs the action, or only plans it in dry-run mode, and prints the results.
+// The action and its past form name what is done in messages, e.g. "link" and "linked".
+func deploy(run, plan func(app.RestoreOptions) (*app.CollectResult, error), opts app.RestoreOptions, dryRun bool, action, past string) {
+	if dryRun {
+		result, err := plan(opts)
+		var errs fileops.Errors
+		if err != nil && !errors.As(err, &errs) {
+			fmt.Printf("Failed to plan %s: %v\n", action, err)
+			os.Exit(1)
+		}
+		fmt.Print(formatPlan(result.Plan))
+		fmt.Println("\nDry run, nothing has been changed.")
+		fmt.Print(formatResult(result))
+		if len(errs) > 0 {
+			fmt.Printf("\n%d files can't be %s:\n%s\n", len(errs), past, formatErrors(errs))
+			os.Exit(1)
+		}
+		return
+	}
+
+	result, err := run(opts)
+	var errs fileops.Errors
+	if err != nil && !errors.As(err, &errs) {
+		fmt.Printf("Failed to %s files: %v\n", action, err)
+		os.Exit(1)
+	}
+
+	if len(errs) > 0 {
+		fmt.Printf("%s the files with errors.\n", strings.ToUpper(past[:1])+past[1:])
+	} else {
+		fmt.Printf("Successfully %s the files.\n", past)
+	}
+	fmt.Print(formatResult(result))
+	if len(errs) > 0 {
+		fmt.Printf("\n%d files failed to %s:\n%s\n", len(errs), action, formatErrors(errs))
+		os.Exit(1)
+	}
+}
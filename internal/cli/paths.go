@@ -2,6 +2,7 @@ package cli
 
 import (
 	"fmt"
+	"os"
 	"strings"
 
 	"github.com/chtozamm/dotfiles-collector/internal/app"
@@ -9,10 +10,12 @@ import (
 )
 
 func setupPathsCmd(app *app.Application, rootCmd *cobra.Command) {
+	var symlinks string
+
 	pathsCmd := &cobra.Command{
-		Use:   "paths <add|list|remove>",
+		Use:   "paths <add|list|remove|enable|disable>",
 		Short: "Manage source paths",
-		Long:  "List, add or remove source paths from the collector.",
+		Long:  "List, add, remove, enable or disable source paths of the collector.",
 	}
 
 	addPath := &cobra.Command{
@@ -29,11 +32,14 @@ func setupPathsCmd(app *app.Application, rootCmd *cobra.Command) {
 			if len(args) == 2 {
 				parentDir = args[1]
 			}
-			err := app.AddCollectPath(args[0], parentDir)
+			warnings, err := app.AddCollectPath(args[0], parentDir, symlinks)
 			if err != nil {
 				fmt.Printf("Failed to add path: %s\n", err)
 				return
 			}
+			for _, warning := range warnings {
+				fmt.Printf("Warning: %s\n", warning)
+			}
 		},
 	}
 
@@ -54,6 +60,44 @@ func setupPathsCmd(app *app.Application, rootCmd *cobra.Command) {
 		},
 	}
 
+	enablePath := &cobra.Command{
+		Use:   "enable <path>...",
+		Short: "Enable disabled paths",
+		Long:  `Enable disabled paths, so that they are collected again.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			if len(args) == 0 {
+				fmt.Println(`Usage:
+  dotfiles-collector paths enable <path>...`)
+				return
+			}
+			for _, path := range args {
+				if err := app.SetCollectPathEnabled(path, true); err != nil {
+					fmt.Printf("Failed to enable path: %s\n", err)
+					os.Exit(1)
+				}
+			}
+		},
+	}
+
+	disablePath := &cobra.Command{
+		Use:   "disable <path>...",
+		Short: "Disable paths without removing them",
+		Long:  `Disable paths without removing them. Disabled paths and their ignore patterns are kept, but not collected.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			if len(args) == 0 {
+				fmt.Println(`Usage:
+  dotfiles-collector paths disable <path>...`)
+				return
+			}
+			for _, path := range args {
+				if err := app.SetCollectPathEnabled(path, false); err != nil {
+					fmt.Printf("Failed to disable path: %s\n", err)
+					os.Exit(1)
+				}
+			}
+		},
+	}
+
 	listPaths := &cobra.Command{
 		Use:   "list",
 		Short: "List source paths added to the collector",
@@ -67,17 +111,30 @@ func setupPathsCmd(app *app.Application, rootCmd *cobra.Command) {
 			}
 			for _, path := range paths {
 				sb.WriteString(path.Path)
+				if path.Portable != "" {
+					sb.WriteString(", portable: " + path.Portable)
+				}
 				if path.Subdir != "" {
 					sb.WriteString(", parent: " + path.Subdir)
 				}
+				if path.Symlinks != "" {
+					sb.WriteString(", symlinks: " + path.Symlinks)
+				}
+				if !path.Enabled {
+					sb.WriteString(", disabled")
+				}
 				sb.WriteString("\n")
 			}
 			fmt.Print(sb.String())
 		},
 	}
 
+	addPath.Flags().StringVar(&symlinks, "symlinks", "", "how to treat symlinks in this path: follow, preserve or skip")
+
 	rootCmd.AddCommand(pathsCmd)
 	pathsCmd.AddCommand(addPath)
 	pathsCmd.AddCommand(removePath)
 	pathsCmd.AddCommand(listPaths)
+	pathsCmd.AddCommand(enablePath)
+	pathsCmd.AddCommand(disablePath)
 }
@@ -0,0 +1,102 @@
+package cli
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/chtozamm/dotfiles-collector/internal/app"
+	"github.com/chtozamm/dotfiles-collector/internal/fileops"
+	"github.com/spf13/cobra"
+)
+
+// Flags of the restore command
+var (
+	restoreDryRun bool
+	restoreYes    bool
+	restoreOpts   app.RestoreOptions
+)
+
+func setupRestoreCmd(app *app.Application, rootCmd *cobra.Command) {
+	restoreCmd := &cobra.Command{
+		Use:   "restore [path...]",
+		Short: "Restore collected files to their source paths",
+		Long: `Restore collected files to their source paths.
+
+Files are copied from the destination back to the paths they were collected from.
+Without arguments, all enabled source paths are restored, otherwise only the given
+ones. Before replacing files that differ from the collected copies, restore asks
+for confirmation, unless --yes is given. If the answer is no, such files are skipped.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			restoreOpts.Paths = args
+
+			// Plan with overwriting enabled to find the files that would be replaced
+			planOpts := restoreOpts
+			planOpts.Overwrite = true
+			plan, err := app.PlanRestoreFiles(planOpts)
+			var errs fileops.Errors
+			if err != nil && !errors.As(err, &errs) {
+				fmt.Printf("Failed to plan restore: %v\n", err)
+				os.Exit(1)
+			}
+
+			if restoreDryRun {
+				fmt.Print(formatPlan(plan.Plan))
+				fmt.Println("\nDry run, nothing has been changed.")
+				fmt.Print(formatResult(plan))
+				if len(errs) > 0 {
+					fmt.Printf("\n%d files can't be restored:\n%s\n", len(errs), formatErrors(errs))
+					os.Exit(1)
+				}
+				return
+			}
+
+			overwritten := plan.Plan.Count(fileops.ActionOverwrite)
+			restoreOpts.Overwrite = restoreYes
+			if overwritten > 0 && !restoreYes {
+				restoreOpts.Overwrite = confirm(fmt.Sprintf("Restoring will overwrite %d files that differ from the collected copies. Overwrite them?", overwritten))
+			}
+
+			result, err := app.RestoreFiles(restoreOpts)
+			errs = nil
+			if err != nil && !errors.As(err, &errs) {
+				fmt.Printf("Failed to restore files: %v\n", err)
+				os.Exit(1)
+			}
+
+			if len(errs) > 0 {
+				fmt.Println("Restored the files with errors.")
+			} else {
+				fmt.Println("Successfully restored the files.")
+			}
+			fmt.Print(formatResult(result))
+			if len(errs) > 0 {
+				fmt.Printf("\n%d files failed to restore:\n%s\n", len(errs), formatErrors(errs))
+				os.Exit(1)
+			}
+		},
+	}
+
+	restoreCmd.Flags().BoolVar(&restoreDryRun, "dry-run", false, "show what would be restored without copying anything")
+	restoreCmd.Flags().BoolVarP(&restoreYes, "yes", "y", false, "overwrite files that differ from the collected copies without asking")
+	restoreCmd.Flags().BoolVar(&restoreOpts.Metadata, "preserve", false, "preserve modes, times and extended attributes of restored files")
+	restoreCmd.Flags().BoolVarP(&restoreOpts.ContinueOnError, "continue-on-error", "k", false, "keep restoring when a file fails to copy and report all failures at the end")
+	restoreCmd.Flags().BoolVar(&restoreOpts.Checksum, "checksum", false, "detect unchanged files by their contents instead of size and modification time")
+
+	rootCmd.AddCommand(restoreCmd)
+}
+
+// confirm asks a yes or no question and reports whether the answer is yes.
+// If the answer can't be read, e.g. when the input isn't a terminal, it is no.
+func confirm(question string) bool {
+	fmt.Printf("%s [y/N] ", question)
+	answer, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil {
+		fmt.Println()
+		return false
+	}
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes"
+}
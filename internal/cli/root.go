@@ -22,6 +22,10 @@ func Execute(app *app.Application) error {
 	setupPathsCmd(app, rootCmd)
 	setupIgnoreCmd(app, rootCmd)
 	setupCollectCmd(app, rootCmd)
+	setupRestoreCmd(app, rootCmd)
+	setupLinkCmd(app, rootCmd)
+	setupAdoptCmd(app, rootCmd)
+	setupBackupsCmd(app, rootCmd)
 	// setupConfigCmd(app, rootCmd)
 
 	// Execute commands
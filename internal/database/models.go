@@ -8,11 +8,24 @@ type CollectPath struct {
 	ID        int64
 	Path      string
 	ParentDir string
+	Symlinks  string
+	Enabled   bool
+	Portable  string
 	CreatedAt string
 }
 
 type IgnorePattern struct {
 	ID        int64
 	Pattern   string
+	Kind      string
+	SourceID  int64
+	Preset    string
+	Enabled   bool
+	CreatedAt string
+}
+
+type Preset struct {
+	ID        int64
+	Name      string
 	CreatedAt string
 }
@@ -10,30 +10,58 @@ import (
 )
 
 const addCollectPath = `-- name: AddCollectPath :exec
-INSERT INTO collect_paths (path, parent_dir) VALUES (?, ?)
+INSERT INTO collect_paths (path, portable, parent_dir, symlinks) VALUES (?, ?, ?, ?)
 `
 
 type AddCollectPathParams struct {
 	Path      string
+	Portable  string
 	ParentDir string
+	Symlinks  string
 }
 
 func (q *Queries) AddCollectPath(ctx context.Context, arg AddCollectPathParams) error {
-	_, err := q.db.ExecContext(ctx, addCollectPath, arg.Path, arg.ParentDir)
+	_, err := q.db.ExecContext(ctx, addCollectPath,
+		arg.Path,
+		arg.Portable,
+		arg.ParentDir,
+		arg.Symlinks,
+	)
 	return err
 }
 
 const addIgnorePattern = `-- name: AddIgnorePattern :exec
-INSERT INTO ignore_patterns (pattern) VALUES (?)
+INSERT INTO ignore_patterns (pattern, kind, source_id, preset) VALUES (?, ?, ?, ?)
+`
+
+type AddIgnorePatternParams struct {
+	Pattern  string
+	Kind     string
+	SourceID int64
+	Preset   string
+}
+
+func (q *Queries) AddIgnorePattern(ctx context.Context, arg AddIgnorePatternParams) error {
+	_, err := q.db.ExecContext(ctx, addIgnorePattern,
+		arg.Pattern,
+		arg.Kind,
+		arg.SourceID,
+		arg.Preset,
+	)
+	return err
+}
+
+const addPreset = `-- name: AddPreset :exec
+INSERT INTO presets (name) VALUES (?)
 `
 
-func (q *Queries) AddIgnorePattern(ctx context.Context, pattern string) error {
-	_, err := q.db.ExecContext(ctx, addIgnorePattern, pattern)
+func (q *Queries) AddPreset(ctx context.Context, name string) error {
+	_, err := q.db.ExecContext(ctx, addPreset, name)
 	return err
 }
 
 const getCollectPath = `-- name: GetCollectPath :one
-SELECT id, path, parent_dir, created_at FROM collect_paths WHERE path = ?
+SELECT id, path, parent_dir, symlinks, enabled, portable, created_at FROM collect_paths WHERE path = ?
 `
 
 func (q *Queries) GetCollectPath(ctx context.Context, path string) (CollectPath, error) {
@@ -43,13 +71,16 @@ func (q *Queries) GetCollectPath(ctx context.Context, path string) (CollectPath,
 		&i.ID,
 		&i.Path,
 		&i.ParentDir,
+		&i.Symlinks,
+		&i.Enabled,
+		&i.Portable,
 		&i.CreatedAt,
 	)
 	return i, err
 }
 
 const getCollectPaths = `-- name: GetCollectPaths :many
-SELECT id, path, parent_dir, created_at FROM collect_paths
+SELECT id, path, parent_dir, symlinks, enabled, portable, created_at FROM collect_paths
 `
 
 func (q *Queries) GetCollectPaths(ctx context.Context) ([]CollectPath, error) {
@@ -65,6 +96,9 @@ func (q *Queries) GetCollectPaths(ctx context.Context) ([]CollectPath, error) {
 			&i.ID,
 			&i.Path,
 			&i.ParentDir,
+			&i.Symlinks,
+			&i.Enabled,
+			&i.Portable,
 			&i.CreatedAt,
 		); err != nil {
 			return nil, err
@@ -81,18 +115,32 @@ func (q *Queries) GetCollectPaths(ctx context.Context) ([]CollectPath, error) {
 }
 
 const getIgnorePattern = `-- name: GetIgnorePattern :one
-SELECT id, pattern, created_at FROM ignore_patterns WHERE pattern = ?
+SELECT id, pattern, kind, source_id, preset, enabled, created_at FROM ignore_patterns WHERE pattern = ? AND kind = ? AND source_id = ?
 `
 
-func (q *Queries) GetIgnorePattern(ctx context.Context, pattern string) (IgnorePattern, error) {
-	row := q.db.QueryRowContext(ctx, getIgnorePattern, pattern)
+type GetIgnorePatternParams struct {
+	Pattern  string
+	Kind     string
+	SourceID int64
+}
+
+func (q *Queries) GetIgnorePattern(ctx context.Context, arg GetIgnorePatternParams) (IgnorePattern, error) {
+	row := q.db.QueryRowContext(ctx, getIgnorePattern, arg.Pattern, arg.Kind, arg.SourceID)
 	var i IgnorePattern
-	err := row.Scan(&i.ID, &i.Pattern, &i.CreatedAt)
+	err := row.Scan(
+		&i.ID,
+		&i.Pattern,
+		&i.Kind,
+		&i.SourceID,
+		&i.Preset,
+		&i.Enabled,
+		&i.CreatedAt,
+	)
 	return i, err
 }
 
 const getIgnorePatterns = `-- name: GetIgnorePatterns :many
-SELECT id, pattern, created_at FROM ignore_patterns
+SELECT id, pattern, kind, source_id, preset, enabled, created_at FROM ignore_patterns ORDER BY id
 `
 
 func (q *Queries) GetIgnorePatterns(ctx context.Context) ([]IgnorePattern, error) {
@@ -104,7 +152,15 @@ func (q *Queries) GetIgnorePatterns(ctx context.Context) ([]IgnorePattern, error
 	var items []IgnorePattern
 	for rows.Next() {
 		var i IgnorePattern
-		if err := rows.Scan(&i.ID, &i.Pattern, &i.CreatedAt); err != nil {
+		if err := rows.Scan(
+			&i.ID,
+			&i.Pattern,
+			&i.Kind,
+			&i.SourceID,
+			&i.Preset,
+			&i.Enabled,
+			&i.CreatedAt,
+		); err != nil {
 			return nil, err
 		}
 		items = append(items, i)
@@ -118,20 +174,135 @@ func (q *Queries) GetIgnorePatterns(ctx context.Context) ([]IgnorePattern, error
 	return items, nil
 }
 
+const getPresets = `-- name: GetPresets :many
+SELECT name FROM presets ORDER BY name
+`
+
+func (q *Queries) GetPresets(ctx context.Context) ([]string, error) {
+	rows, err := q.db.QueryContext(ctx, getPresets)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		items = append(items, name)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const removeCollectPath = `-- name: RemoveCollectPath :exec
-DELETE FROM collect_paths WHERE path = ?
+DELETE FROM collect_paths WHERE id = ?
 `
 
-func (q *Queries) RemoveCollectPath(ctx context.Context, path string) error {
-	_, err := q.db.ExecContext(ctx, removeCollectPath, path)
+func (q *Queries) RemoveCollectPath(ctx context.Context, id int64) error {
+	_, err := q.db.ExecContext(ctx, removeCollectPath, id)
 	return err
 }
 
-const removeIgnorePattern = `-- name: RemoveIgnorePattern :exec
-DELETE FROM ignore_patterns WHERE pattern = ?
+const removeIgnorePattern = `-- name: RemoveIgnorePattern :execrows
+DELETE FROM ignore_patterns WHERE pattern = ? AND kind = ? AND source_id = ?
 `
 
-func (q *Queries) RemoveIgnorePattern(ctx context.Context, pattern string) error {
-	_, err := q.db.ExecContext(ctx, removeIgnorePattern, pattern)
+type RemoveIgnorePatternParams struct {
+	Pattern  string
+	Kind     string
+	SourceID int64
+}
+
+func (q *Queries) RemoveIgnorePattern(ctx context.Context, arg RemoveIgnorePatternParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, removeIgnorePattern, arg.Pattern, arg.Kind, arg.SourceID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const removePreset = `-- name: RemovePreset :exec
+DELETE FROM presets WHERE name = ?
+`
+
+func (q *Queries) RemovePreset(ctx context.Context, name string) error {
+	_, err := q.db.ExecContext(ctx, removePreset, name)
+	return err
+}
+
+const removePresetIgnorePatterns = `-- name: RemovePresetIgnorePatterns :exec
+DELETE FROM ignore_patterns WHERE preset = ?
+`
+
+func (q *Queries) RemovePresetIgnorePatterns(ctx context.Context, preset string) error {
+	_, err := q.db.ExecContext(ctx, removePresetIgnorePatterns, preset)
+	return err
+}
+
+const removeSourceIgnorePatterns = `-- name: RemoveSourceIgnorePatterns :exec
+DELETE FROM ignore_patterns WHERE source_id = ?
+`
+
+func (q *Queries) RemoveSourceIgnorePatterns(ctx context.Context, sourceID int64) error {
+	_, err := q.db.ExecContext(ctx, removeSourceIgnorePatterns, sourceID)
 	return err
 }
+
+const setCollectPathEnabled = `-- name: SetCollectPathEnabled :exec
+UPDATE collect_paths SET enabled = ? WHERE id = ?
+`
+
+type SetCollectPathEnabledParams struct {
+	Enabled bool
+	ID      int64
+}
+
+func (q *Queries) SetCollectPathEnabled(ctx context.Context, arg SetCollectPathEnabledParams) error {
+	_, err := q.db.ExecContext(ctx, setCollectPathEnabled, arg.Enabled, arg.ID)
+	return err
+}
+
+const setCollectPathPortable = `-- name: SetCollectPathPortable :exec
+UPDATE collect_paths SET portable = ? WHERE id = ?
+`
+
+type SetCollectPathPortableParams struct {
+	Portable string
+	ID       int64
+}
+
+func (q *Queries) SetCollectPathPortable(ctx context.Context, arg SetCollectPathPortableParams) error {
+	_, err := q.db.ExecContext(ctx, setCollectPathPortable, arg.Portable, arg.ID)
+	return err
+}
+
+const setIgnorePatternEnabled = `-- name: SetIgnorePatternEnabled :execrows
+UPDATE ignore_patterns SET enabled = ? WHERE pattern = ? AND kind = ? AND source_id = ?
+`
+
+type SetIgnorePatternEnabledParams struct {
+	Enabled  bool
+	Pattern  string
+	Kind     string
+	SourceID int64
+}
+
+func (q *Queries) SetIgnorePatternEnabled(ctx context.Context, arg SetIgnorePatternEnabledParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, setIgnorePatternEnabled,
+		arg.Enabled,
+		arg.Pattern,
+		arg.Kind,
+		arg.SourceID,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
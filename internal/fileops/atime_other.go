@@ -0,0 +1,14 @@
+//go:build !linux && !darwin && !freebsd && !netbsd && !windows
+
+package fileops
+
+import (
+	"os"
+	"time"
+)
+
+// accessTime returns the modification time of the file,
+// as the access time is not available on this platform.
+func accessTime(info os.FileInfo) time.Time {
+	return info.ModTime()
+}
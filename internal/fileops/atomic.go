@@ -0,0 +1,142 @@
+package fileops
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// tempPattern returns a pattern for a temporary file next to the destination.
+// The file is hidden and has a distinct suffix, so it's easy to spot if left behind.
+func tempPattern(dst string) string {
+	return "." + filepath.Base(dst) + ".*.tmp"
+}
+
+// isTempFile reports whether the name is one a temporary file created with
+// tempPattern gets, e.g. ".bashrc.123456.tmp". Such files are left behind
+// if the program is interrupted before the rename.
+func isTempFile(name string) bool {
+	rest, ok := strings.CutSuffix(name, ".tmp")
+	if !ok || !strings.HasPrefix(rest, ".") {
+		return false
+	}
+	i := strings.LastIndexByte(rest, '.')
+	if i <= 0 || i == len(rest)-1 {
+		return false
+	}
+	for _, r := range rest[i+1:] {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// syncDir flushes the directory to disk, so that a file renamed into it
+// survives a crash. Directories can't be flushed on Windows, where
+// a rename is persisted by the file system itself.
+func syncDir(dir string) error {
+	if runtime.GOOS == "windows" {
+		return nil
+	}
+	f, err := os.Open(dir)
+	if err != nil {
+		return fmt.Errorf("open directory %q: %w", dir, err)
+	}
+	defer f.Close()
+	if err := f.Sync(); err != nil {
+		return fmt.Errorf("sync directory %q: %w", dir, err)
+	}
+	return nil
+}
+
+// writeFileAtomic copies contents of the source to a temporary file in the
+// destination directory, flushes it to disk and renames it over the destination,
+// so the destination either keeps the previous contents or has the new ones.
+func writeFileAtomic(src, dst string, srcFileInfo os.FileInfo, metadata bool) (err error) {
+	// Open source file
+	srcFile, err := os.Open(filepath.Clean(src))
+	if err != nil {
+		return fmt.Errorf("open source file %q: %w", src, err)
+	}
+	defer srcFile.Close()
+
+	// Create temporary file in the destination directory
+	tmpFile, err := os.CreateTemp(filepath.Dir(dst), tempPattern(dst))
+	if err != nil {
+		return fmt.Errorf("create temporary file for %q: %w", dst, err)
+	}
+	tmpPath := tmpFile.Name()
+	defer func() {
+		if err != nil {
+			tmpFile.Close()
+			os.Remove(tmpPath)
+		}
+	}()
+
+	// Copy contents from source to the temporary file and flush them to disk
+	if _, err = io.Copy(tmpFile, srcFile); err != nil {
+		return fmt.Errorf("copy %q to %q: %w", src, dst, err)
+	}
+	if err = tmpFile.Chmod(srcFileInfo.Mode().Perm()); err != nil {
+		return fmt.Errorf("set mode of %q: %w", tmpPath, err)
+	}
+	if err = tmpFile.Sync(); err != nil {
+		return fmt.Errorf("sync %q: %w", tmpPath, err)
+	}
+	if err = tmpFile.Close(); err != nil {
+		return fmt.Errorf("close %q: %w", tmpPath, err)
+	}
+
+	if metadata {
+		if err = applyMetadata(src, tmpPath, srcFileInfo); err != nil {
+			return err
+		}
+	} else {
+		// Carry over modification time so that unchanged files can be detected on the next run
+		if err = os.Chtimes(tmpPath, time.Time{}, srcFileInfo.ModTime()); err != nil {
+			return fmt.Errorf("set modification time of %q: %w", tmpPath, err)
+		}
+	}
+
+	// Replace the destination with the complete file
+	if err = os.Rename(tmpPath, dst); err != nil {
+		return fmt.Errorf("rename %q to %q: %w", tmpPath, dst, err)
+	}
+	return syncDir(filepath.Dir(dst))
+}
+
+// symlinkAtomic creates a symbolic link with a temporary name in the destination
+// directory and renames it over the destination. A directory at the destination
+// can't be replaced by renaming, so it is removed first if it is empty.
+func symlinkAtomic(target, dst string) error {
+	if info, err := os.Lstat(dst); err == nil && info.IsDir() {
+		if err := os.Remove(dst); err != nil {
+			return fmt.Errorf("remove %q: %w", dst, err)
+		}
+	}
+
+	// Reserve a unique name for the link
+	tmpFile, err := os.CreateTemp(filepath.Dir(dst), tempPattern(dst))
+	if err != nil {
+		return fmt.Errorf("create temporary file for %q: %w", dst, err)
+	}
+	tmpPath := tmpFile.Name()
+	tmpFile.Close()
+	if err := os.Remove(tmpPath); err != nil {
+		return fmt.Errorf("remove %q: %w", tmpPath, err)
+	}
+
+	if err := os.Symlink(target, tmpPath); err != nil {
+		return fmt.Errorf("create symlink %q: %w", dst, err)
+	}
+	if err := os.Rename(tmpPath, dst); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("rename %q to %q: %w", tmpPath, dst, err)
+	}
+	return syncDir(filepath.Dir(dst))
+}
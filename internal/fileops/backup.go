@@ -0,0 +1,82 @@
+package fileops
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// BackupPath returns where the file at the absolute path is saved inside the
+// backup directory: the path is kept, with the volume name of Windows paths
+// turned into a directory, e.g. "C:\Users" is saved as "C\Users".
+func BackupPath(dir, path string) string {
+	volume := filepath.VolumeName(path)
+	return filepath.Join(dir, strings.TrimSuffix(volume, ":"), strings.TrimPrefix(path, volume))
+}
+
+// OriginalPath returns the absolute path of the file saved at the backup path
+// inside the backup directory. It reverses BackupPath.
+func OriginalPath(dir, backup string) (string, error) {
+	rel, err := filepath.Rel(dir, backup)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("%q is not inside backup %q", backup, dir)
+	}
+	if filepath.VolumeName(dir) != "" {
+		// The first directory is the volume name without the colon
+		volume, rest, _ := strings.Cut(rel, string(filepath.Separator))
+		return volume + ":" + string(filepath.Separator) + rest, nil
+	}
+	return string(filepath.Separator) + rel, nil
+}
+
+// backup saves the file at the destination into the backup directory before
+// it is replaced with the source. Nothing is saved if the Backup option isn't
+// set, if there is no file at the destination, or if it has the same contents
+// as the source. A directory is moved into the backup as a whole, since it can
+// only be replaced once it is out of the way.
+func (c *copier) backup(src, dst string) error {
+	if c.opts.Backup == "" {
+		return nil
+	}
+	info, err := os.Lstat(dst)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return fmt.Errorf("stat file: %w", err)
+	}
+	// Nothing is lost by replacing a file that doesn't differ
+	if info.Mode().IsRegular() {
+		if srcInfo, err := os.Stat(src); err == nil && srcInfo.Mode().IsRegular() {
+			if unchanged, err := isUnchanged(src, dst, srcInfo, info, true); err == nil && unchanged {
+				return nil
+			}
+		}
+	}
+
+	path := BackupPath(c.opts.Backup, dst)
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return fmt.Errorf("create backup directory %q: %w", filepath.Dir(path), err)
+	}
+	if info.IsDir() {
+		if err := Move(dst, filepath.Dir(path)); err != nil {
+			return fmt.Errorf("back up %q: %w", dst, err)
+		}
+		return nil
+	}
+	if info.Mode()&os.ModeSymlink != 0 {
+		target, err := os.Readlink(dst)
+		if err != nil {
+			return fmt.Errorf("read symlink %q: %w", dst, err)
+		}
+		if err := symlinkAtomic(target, path); err != nil {
+			return fmt.Errorf("back up %q: %w", dst, err)
+		}
+		return nil
+	}
+	if err := writeFileAtomic(dst, path, info, true); err != nil {
+		return fmt.Errorf("back up %q: %w", dst, err)
+	}
+	return nil
+}
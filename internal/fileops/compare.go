@@ -0,0 +1,48 @@
+package fileops
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"os"
+)
+
+// isUnchanged reports whether the destination file is up to date with the source.
+//
+// Files of different size are always considered changed. Otherwise, the modification
+// times are compared, or, if checksum is set, the SHA-256 hashes of the contents.
+func isUnchanged(src, dst string, srcInfo, dstInfo os.FileInfo, checksum bool) (bool, error) {
+	if srcInfo.Size() != dstInfo.Size() {
+		return false, nil
+	}
+
+	if !checksum {
+		return srcInfo.ModTime().Equal(dstInfo.ModTime()), nil
+	}
+
+	srcHash, err := hashFile(src)
+	if err != nil {
+		return false, err
+	}
+	dstHash, err := hashFile(dst)
+	if err != nil {
+		return false, err
+	}
+	return bytes.Equal(srcHash, dstHash), nil
+}
+
+// hashFile returns the SHA-256 hash of the file contents.
+func hashFile(path string) ([]byte, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("open file %q: %w", path, err)
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return nil, fmt.Errorf("hash file %q: %w", path, err)
+	}
+	return h.Sum(nil), nil
+}
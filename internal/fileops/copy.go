@@ -2,129 +2,394 @@ package fileops
 
 import (
 	"fmt"
-	"io"
 	"os"
 	"path/filepath"
 )
 
-// Copy copies a file or a directory to a specified destination.
+// Options configures how Copy and PlanCopy treat the source and the destination.
+type Options struct {
+	Overwrite       bool          // Replace files that already exist in the destination
+	CreateDst       bool          // Create destination directory if it doesn't exist
+	Ignore          *Matcher      // Paths to skip, nothing is skipped if nil
+	Checksum        bool          // Compare contents instead of size and modification time
+	Symlinks        SymlinkPolicy // How to treat symbolic links found in the source
+	Metadata        bool          // Preserve modes, times and extended attributes
+	Jobs            int           // Number of files copied in parallel, one if not set
+	Mirror          bool          // Remove files of a source directory that aren't in the source anymore
+	ContinueOnError bool          // Carry on after a file fails to copy and return all failures as Errors
+	Exclude         []string      // Directories that are never walked, such as the destination itself
+	Backup          string        // Directory to save replaced files to, nothing is saved if empty
+}
+
+// copier walks a source and either copies it or only records the plan.
+type copier struct {
+	opts      Options
+	dryRun    bool
+	plan      *Plan
+	ignore    ignoreRules         // Patterns that apply to the paths of the source
+	ancestors []os.FileInfo       // Directories being copied, used to detect symlink loops
+	dirs      []dirMetadata       // Copied directories to apply metadata to once they are complete
+	pool      *pool               // Workers writing files, nil if files are copied one at a time
+	expected  map[string]struct{} // Destination paths that belong to the source in mirror mode
+	kept      map[string]struct{} // Destination paths kept in mirror mode together with everything under them
+	excluded  []os.FileInfo       // Directories from the Exclude option that exist on disk
+}
+
+// newCopier returns a copier with the given options. If dryRun is set,
+// the copier only records the plan and doesn't modify the disk.
+func newCopier(opts Options, dryRun bool) *copier {
+	c := &copier{opts: opts, dryRun: dryRun, plan: &Plan{}, expected: make(map[string]struct{}), kept: make(map[string]struct{})}
+	for _, path := range opts.Exclude {
+		// Paths that don't exist yet can't be reached by the walk
+		if info, err := os.Stat(path); err == nil {
+			c.excluded = append(c.excluded, info)
+		}
+	}
+	return c
+}
+
+// Source is a file or a directory copied by CopyAll to a specified destination.
+type Source struct {
+	Src      string
+	Dst      string
+	Ignore   *Matcher      // Paths of the source to skip, nothing is skipped if nil
+	Symlinks SymlinkPolicy // How to treat symbolic links found in the source
+}
+
+// Copy copies a file or a directory to a specified destination
+// and returns the plan it has carried out.
 //
 // Optionally, it can overwrite existing files and create destination directory
 // if it doesn't exist. If ignore patterns are provided, it can check source against them
 // and skip copying if match is found.
-func Copy(src, dst string, overwrite bool, createDst bool, ignorePatterns []string) error {
-	// Return if source is in the ignore list
-	if shouldIgnorePath(src, ignorePatterns) {
+//
+// By default, Copy stops at the first file that fails to copy. If ContinueOnError
+// is set, it copies everything it can and returns the failures as Errors.
+func Copy(src, dst string, opts Options) (*Plan, error) {
+	plans, errs := CopyAll([]Source{{Src: src, Dst: dst, Ignore: opts.Ignore, Symlinks: opts.Symlinks}}, opts)
+	return plans[0], errs[0]
+}
+
+// CopyAll copies the sources the way Copy does and returns the plan and
+// the error of each of them. The Ignore and Symlinks options are taken from
+// the sources instead of opts.
+//
+// The sources share the workers, so the files of one source are written
+// while the next one is walked. In mirror mode, the destinations are pruned
+// once every source is copied, so that no source removes the files another
+// one has written. Unless ContinueOnError is set, CopyAll stops at the first
+// source that fails, which is the last one it returns.
+func CopyAll(sources []Source, opts Options) ([]*Plan, []error) {
+	return copyAll(sources, opts, false)
+}
+
+// copyAll copies the sources or only plans the copy if dryRun is set.
+func copyAll(sources []Source, opts Options, dryRun bool) ([]*Plan, []error) {
+	var p *pool
+	if opts.Jobs > 1 {
+		p = newPool(opts.Jobs)
+	}
+
+	copiers := make([]*copier, 0, len(sources))
+	walkErrs := make([]error, 0, len(sources))
+	expected := make(map[string]struct{})
+	kept := make(map[string]struct{})
+	for _, source := range sources {
+		srcOpts := opts
+		srcOpts.Ignore = source.Ignore
+		srcOpts.Symlinks = source.Symlinks
+		c := newCopier(srcOpts, dryRun)
+		c.pool, c.expected, c.kept = p, expected, kept
+
+		err := c.copy(source.Src, source.Dst)
+		copiers = append(copiers, c)
+		walkErrs = append(walkErrs, err)
+		if !opts.ContinueOnError && (err != nil || len(c.plan.Errors()) > 0 || (p != nil && p.failed())) {
+			break
+		}
+	}
+	if p != nil {
+		p.wait()
+	}
+
+	// Files are handled in the order they were found, so the earliest failed
+	// entry is the same no matter how the tasks were scheduled
+	plans := make([]*Plan, len(copiers))
+	errs := make([]error, len(copiers))
+	failed := false
+	for i, c := range copiers {
+		plans[i] = c.plan
+		entryErrs := c.plan.Errors()
+		switch {
+		case len(entryErrs) > 0 && !opts.ContinueOnError:
+			errs[i], failed = entryErrs[0], true
+		case walkErrs[i] != nil:
+			errs[i], failed = walkErrs[i], failed || !opts.ContinueOnError
+		case len(entryErrs) > 0:
+			errs[i] = entryErrs
+		}
+	}
+	if failed {
+		return plans, errs
+	}
+
+	// Prune and apply metadata only once every source is complete
+	for i, c := range copiers {
+		if walkErrs[i] != nil {
+			continue
+		}
+		if err := c.mirror(sources[i].Src, sources[i].Dst); err != nil {
+			errs[i] = err
+			continue
+		}
+		if !dryRun {
+			if err := c.applyDirMetadata(); err != nil {
+				errs[i] = err
+			}
+		}
+	}
+	return plans, errs
+}
+
+// fail records the error on the plan entry with the given index. In best-effort
+// mode the copier carries on, otherwise the returned error stops the walk.
+func (c *copier) fail(index int, err error) error {
+	fileErr := NewFileError(c.plan.Entries[index].Src, err)
+	c.plan.Entries[index].Err = fileErr
+	if c.opts.ContinueOnError {
+		return nil
+	}
+	return fileErr
+}
+
+// skipFailed adds an entry for the source that couldn't be processed and records the error.
+// Whatever was collected from the source before is kept, even in mirror mode.
+func (c *copier) skipFailed(entry Entry, err error) error {
+	entry.Action = ActionSkip
+	c.plan.add(entry)
+	c.keep(entry.Dst)
+	return c.fail(len(c.plan.Entries)-1, err)
+}
+
+// mirror prunes the destination of a directory source if mirror mode is enabled.
+func (c *copier) mirror(src, dst string) error {
+	if !c.opts.Mirror || !doesDirExist(src) {
 		return nil
 	}
+	return c.prune(filepath.Join(dst, filepath.Base(src)))
+}
 
+// copy checks the source and the destination and calls appropriate copy function.
+func (c *copier) copy(src, dst string) error {
 	// Check if source file exists
-	srcFileInfo, err := os.Stat(src)
+	srcFileInfo, err := os.Lstat(src)
 	if err != nil {
 		if os.IsNotExist(err) {
 			return fmt.Errorf("source %q does not exist", src)
 		}
-		return fmt.Errorf("stat file: %v", err)
+		return fmt.Errorf("stat file: %w", err)
 	}
 
+	c.ignore = newIgnoreRules(src, c.opts.Ignore)
+
 	// Check if destination directory exists
-	if !doesDirExist(dst) && !createDst {
-		return fmt.Errorf("destination %q does not exist and createDst is set to false", src)
+	if !doesDirExist(dst) && !c.opts.CreateDst {
+		return fmt.Errorf("destination %q does not exist and createDst is set to false", dst)
 	}
 
 	// Call appropriate copy function
 	if srcFileInfo.IsDir() {
-		return copyDirectory(src, dst, overwrite, ignorePatterns)
+		return c.copyDirectory(src, dst)
 	}
-	return copyFile(src, dst, overwrite, ignorePatterns)
+	return c.copyFile(src, dst)
 }
 
-// copyFile copies a file to a specified destination.
-func copyFile(src, dst string, overwrite bool, ignorePatterns []string) error {
-	// Return if source is in the ignore list
-	if shouldIgnorePath(src, ignorePatterns) {
-		return nil
-	}
-
+// copyFile copies a file or a symbolic link to a specified destination.
+func (c *copier) copyFile(src, dst string) error {
 	// Append file name to destination path
 	dst = filepath.Join(dst, filepath.Base(src))
 
-	// Check if the destination file exists
-	if !overwrite {
-		if _, err := os.Stat(dst); err == nil {
-			if os.IsExist(err) {
-				return fmt.Errorf("destination file %q already exists and overwrite is set to false", dst)
-			}
-			return fmt.Errorf("stat file: %v", err)
-		}
+	// Skip the source if it is in the ignore list
+	if c.ignore.isIgnored(src, false) {
+		c.plan.add(Entry{Action: ActionIgnore, Src: src, Dst: dst})
+		return nil
 	}
 
-	// Create parent directory if it doesn't exist
-	if err := os.MkdirAll(filepath.Dir(dst), 0740); err != nil {
-		return fmt.Errorf("create directory %q: %v", filepath.Dir(dst), err)
+	srcFileInfo, err := os.Lstat(src)
+	if err != nil {
+		return c.skipFailed(Entry{Src: src, Dst: dst}, fmt.Errorf("stat source file %q: %w", src, err))
 	}
 
-	// Open source file
-	srcFile, err := os.Open(filepath.Clean(src))
-	if err != nil {
-		return fmt.Errorf("open source file %q: %v", src, err)
+	if srcFileInfo.Mode()&os.ModeSymlink != 0 {
+		return c.copySymlink(src, dst)
 	}
-	defer srcFile.Close()
+	return c.copyRegularFile(src, dst, srcFileInfo)
+}
 
-	srcFileInfo, err := srcFile.Stat()
-	if err != nil {
-		return fmt.Errorf("stat source file %q: %v", src, err)
+// copyRegularFile copies contents of a file to the full destination path.
+func (c *copier) copyRegularFile(src, dst string, srcFileInfo os.FileInfo) error {
+	// Sockets, named pipes and devices can't be copied
+	if !srcFileInfo.Mode().IsRegular() {
+		return c.skipFailed(Entry{Src: src, Dst: dst}, fmt.Errorf("copy %q: %w", src, ErrSpecialFile))
 	}
 
-	// Open or create destination file
-	dstFile, err := os.OpenFile(filepath.Clean(dst), os.O_WRONLY|os.O_CREATE|os.O_TRUNC, srcFileInfo.Mode())
-	if err != nil {
-		return fmt.Errorf("open destination file %q: %v", dst, err)
+	// Check if the destination file exists, it is compared with the source below
+	action := ActionCreate
+	compare := false
+	dstFileInfo, err := os.Lstat(dst)
+	if err == nil {
+		action = ActionOverwrite
+		// A link left in the destination is always replaced, never written through
+		compare = dstFileInfo.Mode()&os.ModeSymlink == 0
+	} else if !os.IsNotExist(err) {
+		return c.skipFailed(Entry{Src: src, Dst: dst}, fmt.Errorf("stat file: %w", err))
 	}
-	defer dstFile.Close()
+	c.plan.add(Entry{Action: action, Src: src, Dst: dst, Size: srcFileInfo.Size()})
+	c.expect(dst)
 
-	// Copy contents from source to destination
-	if _, err := io.Copy(dstFile, srcFile); err != nil {
-		return fmt.Errorf("copy %q to %q: %v", src, dst, err)
+	// Comparing contents can take as long as copying them, so both are done by the workers
+	task := func() (Action, error) {
+		if compare {
+			unchanged, err := isUnchanged(src, dst, srcFileInfo, dstFileInfo, c.opts.Checksum)
+			if err != nil {
+				return ActionSkip, err
+			}
+			// Changed permissions are carried over even if the contents are the same
+			if c.opts.Metadata && preservedMode(srcFileInfo) != preservedMode(dstFileInfo) {
+				unchanged = false
+			}
+			if unchanged {
+				return ActionUnchanged, nil
+			}
+		}
+		if action == ActionOverwrite && !c.opts.Overwrite {
+			return ActionSkip, nil
+		}
+		if c.dryRun {
+			return action, nil
+		}
+		return action, c.writeFile(src, dst, srcFileInfo)
 	}
 
+	index := len(c.plan.Entries) - 1
+	if c.pool == nil {
+		action, err := task()
+		c.plan.Entries[index].Action = action
+		if err != nil {
+			return c.fail(index, err)
+		}
+		return nil
+	}
+	c.pool.run(c.plan, index, task)
 	return nil
 }
 
-// copyDirectory copies a directory and its contents to a specified destination.
-func copyDirectory(src, dst string, overwrite bool, ignorePatterns []string) error {
-	// Return if source is in the ignore list
-	if shouldIgnorePath(src, ignorePatterns) {
-		return nil
+// writeFile creates parent directory of the destination and writes the file.
+// The file it replaces is backed up first if it differs from the source.
+func (c *copier) writeFile(src, dst string, srcFileInfo os.FileInfo) error {
+	if err := c.backup(src, dst); err != nil {
+		return err
 	}
 
+	// Create parent directory if it doesn't exist
+	if err := os.MkdirAll(filepath.Dir(dst), 0740); err != nil {
+		return fmt.Errorf("create directory %q: %w", filepath.Dir(dst), err)
+	}
+
+	return writeFileAtomic(src, dst, srcFileInfo, c.opts.Metadata)
+}
+
+// copyDirectory copies a directory and its contents to a specified destination.
+func (c *copier) copyDirectory(src, dst string) error {
 	// Append directory name to destination path
 	dst = filepath.Join(dst, filepath.Base(src))
 
+	// Skip the source if it is in the ignore list
+	if c.ignore.isIgnored(src, true) {
+		c.plan.add(Entry{Action: ActionIgnore, Src: src, Dst: dst, IsDir: true})
+		return nil
+	}
+
 	// Check if the source directory exists
-	if !doesDirExist(src) {
-		return fmt.Errorf("source directory %q does not exist", src)
+	srcDirInfo, err := os.Stat(src)
+	if err != nil {
+		return c.skipFailed(Entry{Src: src, Dst: dst, IsDir: true}, fmt.Errorf("stat source directory %q: %w", src, err))
 	}
 
-	// Create the destination directory
-	if err := os.MkdirAll(dst, 0740); err != nil {
-		return fmt.Errorf("create destination directory %q: %v", dst, err)
+	// Never descend into excluded directories, even through a symlink
+	if c.isExcluded(srcDirInfo) {
+		c.plan.add(Entry{Action: ActionSkip, Src: src, Dst: dst, IsDir: true, Reason: reasonExcluded})
+		return nil
+	}
+	c.expect(dst)
+
+	// A link in place of the directory is replaced like a file, unless it leads to the source itself
+	if dstFileInfo, err := os.Lstat(dst); err == nil && dstFileInfo.Mode()&os.ModeSymlink != 0 {
+		switch {
+		case isSameFile(dst, src):
+			c.plan.add(Entry{Action: ActionUnchanged, Src: src, Dst: dst, IsDir: true, Reason: "linked"})
+			c.keep(dst)
+			return nil
+		case !c.opts.Overwrite:
+			c.plan.add(Entry{Action: ActionSkip, Src: src, Dst: dst, IsDir: true, Reason: "symlink exists"})
+			c.keep(dst)
+			return nil
+		}
+		c.plan.add(Entry{Action: ActionOverwrite, Src: src, Dst: dst, IsDir: true})
+		if !c.dryRun {
+			if err := c.backup(src, dst); err != nil {
+				c.keep(dst)
+				return c.fail(len(c.plan.Entries)-1, err)
+			}
+		}
+	}
+
+	c.ancestors = append(c.ancestors, srcDirInfo)
+	defer func() { c.ancestors = c.ancestors[:len(c.ancestors)-1] }()
+
+	// Create the destination directory, replacing the link if there was one.
+	// When preserving metadata, the directory stays writable until its contents are copied.
+	dirMode := os.FileMode(0740)
+	if c.opts.Metadata {
+		dirMode = srcDirInfo.Mode().Perm() | 0700
+		c.dirs = append(c.dirs, dirMetadata{src: src, dst: dst, info: srcDirInfo})
+	}
+	if !c.dryRun {
+		if err := createDir(dst, dirMode, c.opts.Metadata); err != nil {
+			return c.skipFailed(Entry{Src: src, Dst: dst, IsDir: true}, err)
+		}
 	}
 
 	// Read the contents of the source directory
 	entries, err := os.ReadDir(src)
 	if err != nil {
-		return fmt.Errorf("read source directory %q: %v", src, err)
+		return c.skipFailed(Entry{Src: src, Dst: dst, IsDir: true}, fmt.Errorf("read source directory %q: %w", src, err))
+	}
+
+	// Apply the ignore file of the directory to its contents
+	leave, err := c.ignore.enter(src)
+	if err != nil {
+		c.plan.warn("%v", err)
 	}
+	defer leave()
 
 	for _, entry := range entries {
+		// Stop walking once a file has failed to copy
+		if c.pool != nil && c.pool.failed() && !c.opts.ContinueOnError {
+			return nil
+		}
+
 		srcPath := filepath.Join(src, entry.Name())
+		if !entry.IsDir() && isTempFile(entry.Name()) {
+			c.plan.add(Entry{Action: ActionSkip, Src: srcPath, Dst: filepath.Join(dst, entry.Name()), Reason: reasonTemporary})
+			continue
+		}
 		if entry.IsDir() {
-			if err := copyDirectory(srcPath, dst, overwrite, ignorePatterns); err != nil {
+			if err := c.copyDirectory(srcPath, dst); err != nil {
 				return err
 			}
 		} else {
-			if err := copyFile(srcPath, dst, overwrite, ignorePatterns); err != nil {
+			if err := c.copyFile(srcPath, dst); err != nil {
 				return err
 			}
 		}
@@ -132,3 +397,33 @@ func copyDirectory(src, dst string, overwrite bool, ignorePatterns []string) err
 
 	return nil
 }
+
+// isExcluded reports whether the directory is one of the excluded directories.
+func (c *copier) isExcluded(info os.FileInfo) bool {
+	for _, excluded := range c.excluded {
+		if os.SameFile(excluded, info) {
+			return true
+		}
+	}
+	return false
+}
+
+// createDir creates the destination directory with the given mode, replacing
+// a link at the destination. If setMode is set, the mode of an existing
+// directory is changed as well.
+func createDir(dst string, mode os.FileMode, setMode bool) error {
+	if info, err := os.Lstat(dst); err == nil && info.Mode()&os.ModeSymlink != 0 {
+		if err := os.Remove(dst); err != nil {
+			return fmt.Errorf("remove %q: %w", dst, err)
+		}
+	}
+	if err := os.MkdirAll(dst, mode); err != nil {
+		return fmt.Errorf("create destination directory %q: %w", dst, err)
+	}
+	if setMode {
+		if err := os.Chmod(dst, mode); err != nil {
+			return fmt.Errorf("set mode of %q: %w", dst, err)
+		}
+	}
+	return nil
+}
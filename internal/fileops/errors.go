@@ -0,0 +1,92 @@
+package fileops
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"strings"
+)
+
+// ErrSpecialFile is returned for sockets, named pipes and devices,
+// which can't be copied as regular files.
+var ErrSpecialFile = errors.New("special file")
+
+// ErrorKind classifies a failure to copy a single file.
+type ErrorKind int
+
+const (
+	ErrorOther      ErrorKind = iota // Any other failure
+	ErrorPermission                  // Access to the file is denied
+	ErrorVanished                    // File was removed while collecting
+	ErrorSpecial                     // File is a socket, a named pipe or a device
+)
+
+// String returns a short lowercase description of the kind.
+func (k ErrorKind) String() string {
+	switch k {
+	case ErrorPermission:
+		return "permission denied"
+	case ErrorVanished:
+		return "vanished"
+	case ErrorSpecial:
+		return "special file"
+	}
+	return "error"
+}
+
+// FileError is a failure to copy a single file or directory.
+type FileError struct {
+	Path string
+	Kind ErrorKind
+	Err  error
+}
+
+// NewFileError returns a file error for the path with the kind derived from err.
+// If err already is a file error, it is returned as is.
+func NewFileError(path string, err error) *FileError {
+	var fileErr *FileError
+	if errors.As(err, &fileErr) {
+		return fileErr
+	}
+
+	kind := ErrorOther
+	switch {
+	case errors.Is(err, ErrSpecialFile):
+		kind = ErrorSpecial
+	case errors.Is(err, fs.ErrPermission):
+		kind = ErrorPermission
+	case errors.Is(err, fs.ErrNotExist):
+		kind = ErrorVanished
+	}
+	return &FileError{Path: path, Kind: kind, Err: err}
+}
+
+func (e *FileError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *FileError) Unwrap() error {
+	return e.Err
+}
+
+// Errors is a list of failures gathered while copying in best-effort mode.
+type Errors []*FileError
+
+func (e Errors) Error() string {
+	if len(e) == 1 {
+		return e[0].Error()
+	}
+	msgs := make([]string, 0, len(e))
+	for _, err := range e {
+		msgs = append(msgs, err.Error())
+	}
+	return fmt.Sprintf("%d files failed to copy: %s", len(e), strings.Join(msgs, "; "))
+}
+
+func (e Errors) Unwrap() []error {
+	errs := make([]error, 0, len(e))
+	for _, err := range e {
+		errs = append(errs, err)
+	}
+	return errs
+}
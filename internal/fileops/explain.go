@@ -0,0 +1,175 @@
+package fileops
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Decision explains whether Copy ignores a path of the source.
+type Decision struct {
+	Path     string
+	IsDir    bool
+	Ignored  bool
+	Pattern  *IgnorePattern // Pattern that decides, nil if no pattern matches the path
+	Reason   string         // Why Copy skips the path though no pattern ignores it, such as "excluded"
+	Parent   string         // Ignored or skipped directory the path is left out with, empty if the path itself is
+	Children []Decision     // Decisions for the contents of a directory that isn't ignored
+}
+
+// newDecision returns the decision made by the pattern for the path.
+func newDecision(path string, isDir bool, pattern *compiledPattern) Decision {
+	d := Decision{Path: path, IsDir: isDir}
+	if pattern != nil {
+		d.Pattern = &pattern.pattern
+		d.Ignored = !pattern.negate
+	}
+	return d
+}
+
+// Explain reports whether Copy with the same options ignores the path found
+// in the source and which pattern decides it. The path is checked the same way
+// Copy walks the source, so if one of the directories above it is ignored or
+// skipped, the path is left out with it.
+func Explain(src, path string, opts Options) (Decision, error) {
+	c := newCopier(opts, true)
+	c.ignore = newIgnoreRules(src, opts.Ignore)
+	return c.descend(src, path)
+}
+
+// ExplainTree returns the decision for the directory found in the source
+// together with the decisions for its contents, the way Copy walks them.
+// Contents of ignored and skipped directories are left out, as Copy doesn't walk them.
+func ExplainTree(src, dir string, opts Options) (Decision, error) {
+	c := newCopier(opts, true)
+	c.ignore = newIgnoreRules(src, opts.Ignore)
+	d, err := c.descend(src, dir)
+	if err != nil || d.Ignored || d.Reason != "" || !d.IsDir {
+		return d, err
+	}
+	d.Children, err = c.walk(dir)
+	return d, err
+}
+
+// explainEntry returns the decision for the path found in a directory, isDir
+// telling whether it is a directory itself rather than a link to one, and the
+// info of the directory if Copy descends into the path. The checks are made
+// in the order Copy makes them: a link is checked as a file first, and if it is
+// followed to a directory, as a directory again.
+func (c *copier) explainEntry(path string, isDir bool) (Decision, os.FileInfo, error) {
+	if !isDir {
+		if isTempFile(filepath.Base(path)) {
+			return Decision{Path: path, Reason: reasonTemporary}, nil, nil
+		}
+		d := newDecision(path, false, c.ignore.decide(path, false))
+		if d.Ignored {
+			return d, nil, nil
+		}
+		info, err := os.Lstat(path)
+		if err != nil || info.Mode()&os.ModeSymlink == 0 {
+			return d, nil, err
+		}
+		target, reason, err := c.followSymlink(path)
+		if err != nil || reason != "" || target == nil || !target.IsDir() {
+			d.Reason = reason
+			return d, nil, err
+		}
+	}
+
+	d := newDecision(path, true, c.ignore.decide(path, true))
+	if d.Ignored {
+		return d, nil, nil
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return d, nil, err
+	}
+	if c.isExcluded(info) {
+		d.Reason = reasonExcluded
+		return d, nil, nil
+	}
+	return d, info, nil
+}
+
+// isDirEntry reports whether the path is a directory and not a link to one,
+// the way entries of a directory are told apart when reading it.
+func isDirEntry(path string) (bool, error) {
+	info, err := os.Lstat(path)
+	if err != nil {
+		return false, err
+	}
+	return info.IsDir(), nil
+}
+
+// descend checks every path from the source down to the path, applying the
+// ignore files on the way, and returns the decision for the path.
+func (c *copier) descend(src, path string) (Decision, error) {
+	rel, err := filepath.Rel(src, path)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return Decision{}, fmt.Errorf("%q is not inside source %q", path, src)
+	}
+
+	var parts []string
+	if rel != "." {
+		parts = strings.Split(rel, string(filepath.Separator))
+	}
+
+	current := src
+	for i := 0; ; i++ {
+		isDir, err := isDirEntry(current)
+		if err != nil {
+			return Decision{}, err
+		}
+		d, dirInfo, err := c.explainEntry(current, isDir)
+		if err != nil {
+			return Decision{}, err
+		}
+		if i == len(parts) {
+			return d, nil
+		}
+		if dirInfo == nil {
+			if !d.Ignored && d.Reason == "" {
+				return Decision{}, fmt.Errorf("%q is not a directory collecting descends into", current)
+			}
+			// The rest of the path is left out together with the current path
+			d.Parent, d.Path = current, path
+			d.IsDir, _ = isDirEntry(path)
+			return d, nil
+		}
+		c.ancestors = append(c.ancestors, dirInfo)
+		// Problems with ignore files are reported when collecting
+		leave, _ := c.ignore.enter(current)
+		defer leave()
+		current = filepath.Join(current, parts[i])
+	}
+}
+
+// walk returns the decisions for the contents of the directory.
+func (c *copier) walk(dir string) ([]Decision, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("read directory %q: %w", dir, err)
+	}
+	leave, _ := c.ignore.enter(dir)
+	defer leave()
+
+	decisions := make([]Decision, 0, len(entries))
+	for _, entry := range entries {
+		path := filepath.Join(dir, entry.Name())
+		d, dirInfo, err := c.explainEntry(path, entry.IsDir())
+		if err != nil {
+			return nil, err
+		}
+		if dirInfo != nil {
+			c.ancestors = append(c.ancestors, dirInfo)
+			d.Children, err = c.walk(path)
+			c.ancestors = c.ancestors[:len(c.ancestors)-1]
+			if err != nil {
+				return nil, err
+			}
+		}
+		decisions = append(decisions, d)
+	}
+	return decisions, nil
+}
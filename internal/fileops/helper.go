@@ -1,9 +1,8 @@
 package fileops
 
 import (
+	"io"
 	"os"
-	"path/filepath"
-	"regexp"
 )
 
 // doesDirExist checks if a directory exists at the given path.
@@ -12,19 +11,26 @@ func doesDirExist(path string) bool {
 	return err == nil && stat.IsDir()
 }
 
-// shouldIgnorePath returns true if the path matches any of the ignore patterns.
-func shouldIgnorePath(path string, ignorePatterns []string) bool {
-	cleanPath := filepath.Clean(path)
-
-	for _, ignorePath := range ignorePatterns {
-		re, err := regexp.Compile(ignorePath)
-		if err != nil {
-			continue // Skip this ignorePath if there's an error
-		}
-		if re.MatchString(cleanPath) {
-			return true
-		}
+// isEmptyDir checks if the directory at the given path has no entries.
+func isEmptyDir(path string) bool {
+	dir, err := os.Open(path)
+	if err != nil {
+		return false
 	}
+	defer dir.Close()
+	_, err = dir.Readdirnames(1)
+	return err == io.EOF
+}
 
-	return false
+// isSameFile reports whether both paths lead to the same file.
+func isSameFile(a, b string) bool {
+	aInfo, err := os.Stat(a)
+	if err != nil {
+		return false
+	}
+	bInfo, err := os.Stat(b)
+	if err != nil {
+		return false
+	}
+	return os.SameFile(aInfo, bInfo)
 }
@@ -0,0 +1,384 @@
+package fileops
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// PatternKind defines how an ignore pattern is matched against paths.
+type PatternKind int
+
+const (
+	PatternRegex PatternKind = iota // Regular expression matched against the absolute path
+	PatternGlob                     // Gitignore-style glob matched against the path relative to the source directory
+	PatternSize                     // Files larger than the size, such as "5MB"
+	PatternAge                      // Files not modified for longer than the age, such as "2y"
+	PatternType                     // Files of a type: socket, fifo, device or binary
+)
+
+// String returns the name of the kind as accepted by ParsePatternKind.
+func (k PatternKind) String() string {
+	switch k {
+	case PatternRegex:
+		return "regex"
+	case PatternGlob:
+		return "glob"
+	case PatternSize:
+		return "size"
+	case PatternAge:
+		return "age"
+	case PatternType:
+		return "type"
+	}
+	return "unknown"
+}
+
+// ParsePatternKind returns the pattern kind with the given name.
+func ParsePatternKind(name string) (PatternKind, error) {
+	switch name {
+	case "regex":
+		return PatternRegex, nil
+	case "glob":
+		return PatternGlob, nil
+	case "size":
+		return PatternSize, nil
+	case "age":
+		return PatternAge, nil
+	case "type":
+		return PatternType, nil
+	}
+	return 0, fmt.Errorf("unknown pattern kind %q, expected regex, glob, size, age or type", name)
+}
+
+// IgnorePattern is a pattern for paths the collector skips.
+//
+// Glob patterns follow the rules of .gitignore: a pattern without a slash
+// matches a name at any depth, a leading slash or a slash in the middle
+// anchors it to the source directory, or to the parent directory of
+// a source file, and never matches the source itself, a trailing slash
+// matches only directories, "**" matches any number of directories and
+// a leading "!" includes paths ignored by the earlier patterns again.
+//
+// Size, age and type patterns are rules on the attributes of files rather
+// than their paths. They never match directories, and for symbolic links
+// the file the link points to is checked.
+type IgnorePattern struct {
+	Pattern string
+	Kind    PatternKind
+	Source  string // Where the pattern comes from, used to explain matches
+}
+
+// Validate returns an error if the pattern can't be compiled.
+func (p IgnorePattern) Validate() error {
+	_, err := p.compile()
+	return err
+}
+
+// compiledPattern is an ignore pattern translated to a regular expression.
+type compiledPattern struct {
+	pattern IgnorePattern
+	re      *regexp.Regexp
+	rule    fileRule // Rule on file attributes, nil for patterns matched against paths
+	glob    bool
+	negate  bool // Path matching the pattern is included again
+	dirOnly bool // Pattern matches only directories
+}
+
+// compile translates the pattern into a regular expression.
+func (p IgnorePattern) compile() (compiledPattern, error) {
+	if p.Kind == PatternRegex {
+		re, err := regexp.Compile(p.Pattern)
+		if err != nil {
+			return compiledPattern{}, err
+		}
+		return compiledPattern{pattern: p, re: re}, nil
+	}
+	if p.Kind != PatternGlob {
+		rule, err := compileRule(p.Kind, p.Pattern)
+		if err != nil {
+			return compiledPattern{}, err
+		}
+		return compiledPattern{pattern: p, rule: rule}, nil
+	}
+
+	pattern := p.Pattern
+	compiled := compiledPattern{pattern: p, glob: true}
+	if strings.HasPrefix(pattern, "!") {
+		compiled.negate = true
+		pattern = pattern[1:]
+	}
+	if strings.HasSuffix(pattern, "/") {
+		compiled.dirOnly = true
+		pattern = strings.TrimRight(pattern, "/")
+	}
+	if pattern == "" {
+		return compiledPattern{}, fmt.Errorf("empty glob pattern %q", p.Pattern)
+	}
+
+	// A pattern with a slash other than the trailing one is anchored
+	anchored := strings.Contains(pattern, "/")
+	pattern = strings.TrimPrefix(pattern, "/")
+
+	expr, err := globToRegexp(pattern)
+	if err != nil {
+		return compiledPattern{}, fmt.Errorf("glob pattern %q: %w", p.Pattern, err)
+	}
+	if !anchored {
+		expr = "(?:.*/)?" + expr
+	}
+	re, err := regexp.Compile("^" + expr + "$")
+	if err != nil {
+		return compiledPattern{}, fmt.Errorf("glob pattern %q: %w", p.Pattern, err)
+	}
+	compiled.re = re
+	return compiled, nil
+}
+
+// globToRegexp translates the body of a glob pattern into a regular expression.
+func globToRegexp(pattern string) (string, error) {
+	var sb strings.Builder
+	for i := 0; i < len(pattern); i++ {
+		ch := pattern[i]
+		switch ch {
+		case '*':
+			if !strings.HasPrefix(pattern[i:], "**") {
+				sb.WriteString("[^/]*")
+				continue
+			}
+			atStart := i == 0 || pattern[i-1] == '/'
+			switch {
+			case atStart && strings.HasPrefix(pattern[i:], "**/"):
+				// Zero or more directories
+				sb.WriteString("(?:.*/)?")
+				i += 2
+			case atStart && i+2 == len(pattern):
+				// Everything inside the directory
+				sb.WriteString(".*")
+				i++
+			default:
+				// Not a separate path component, same as a single star
+				sb.WriteString("[^/]*")
+				i++
+			}
+		case '?':
+			sb.WriteString("[^/]")
+		case '[':
+			end := strings.IndexByte(pattern[i+1:], ']')
+			if end < 0 {
+				return "", fmt.Errorf("missing closing bracket")
+			}
+			class := pattern[i+1 : i+1+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			sb.WriteString("[" + strings.ReplaceAll(class, `\`, `\\`) + "]")
+			i += end + 1
+		case '\\':
+			// Escaped character is matched literally
+			if i+1 < len(pattern) {
+				i++
+				sb.WriteString(regexp.QuoteMeta(string(pattern[i])))
+			}
+		default:
+			sb.WriteString(regexp.QuoteMeta(string(ch)))
+		}
+	}
+	return sb.String(), nil
+}
+
+// Matcher decides which paths are ignored by a list of patterns.
+// The patterns are compiled once, so a Matcher can be reused for
+// every path of a collection.
+type Matcher struct {
+	patterns []compiledPattern
+}
+
+// NewMatcher compiles the patterns into a matcher. It returns an error
+// if any of the patterns is invalid.
+func NewMatcher(patterns []IgnorePattern) (*Matcher, error) {
+	m := &Matcher{patterns: make([]compiledPattern, 0, len(patterns))}
+	for _, pattern := range patterns {
+		compiled, err := pattern.compile()
+		if err != nil {
+			return nil, fmt.Errorf("ignore pattern %q: %w", pattern.Pattern, err)
+		}
+		m.patterns = append(m.patterns, compiled)
+	}
+	return m, nil
+}
+
+// last returns the last pattern that matches the path, or nil if none does.
+// Regular expressions are matched against the path, globs against the path
+// relative to the root, which must end with a separator, and only if the path
+// is inside the root. Patterns are evaluated in order, so the last matching one
+// decides whether the path is ignored and a negated glob can include a path
+// ignored by an earlier pattern.
+//
+// The path must be clean. Matching paths doesn't allocate, as it runs for
+// every file and directory of the source. Rules on file attributes stat
+// the file once, and only if the matcher has any.
+func (m *Matcher) last(path, root string, isDir bool) *compiledPattern {
+	if m == nil {
+		return nil
+	}
+	inside := strings.HasPrefix(path, root)
+	relPath := filepath.ToSlash(strings.TrimPrefix(path, root))
+	var (
+		info    os.FileInfo
+		statted bool
+	)
+	for i := len(m.patterns) - 1; i >= 0; i-- {
+		pattern := &m.patterns[i]
+		if pattern.rule != nil {
+			if isDir {
+				continue
+			}
+			if !statted {
+				// A file that can't be stat'ed fails later on, so no rule matches it
+				info, _ = os.Stat(path)
+				statted = true
+			}
+			// A link to a directory is checked as a file, but it is walked as a directory when followed
+			if info != nil && !info.IsDir() && pattern.rule(path, info) {
+				return pattern
+			}
+			continue
+		}
+		if !pattern.glob {
+			if pattern.re.MatchString(path) {
+				return pattern
+			}
+			continue
+		}
+		if !inside || pattern.dirOnly && !isDir {
+			continue
+		}
+		if pattern.re.MatchString(relPath) {
+			return pattern
+		}
+	}
+	return nil
+}
+
+// withSeparator returns the directory with a trailing separator.
+func withSeparator(dir string) string {
+	if strings.HasSuffix(dir, string(filepath.Separator)) {
+		return dir
+	}
+	return dir + string(filepath.Separator)
+}
+
+// IgnoreFileName is the name of files with glob patterns for the directory they are in.
+const IgnoreFileName = ".dotfilesignore"
+
+// ignoreFile holds the patterns of an ignore file found while walking the source.
+type ignoreFile struct {
+	dir     string // Directory of the file with a trailing separator, its patterns are relative to it
+	matcher *Matcher
+}
+
+// readIgnoreFile reads glob patterns from the ignore file in the directory.
+// Like in .gitignore, blank lines and lines starting with "#" are skipped.
+// Invalid patterns are left out and returned as an error with the valid ones.
+// If there is no ignore file, it returns nil.
+func readIgnoreFile(dir string) (*ignoreFile, error) {
+	path := filepath.Join(dir, IgnoreFileName)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("read ignore file: %w", err)
+	}
+
+	patterns, errs := parseLines(data, PatternGlob, path)
+	return &ignoreFile{dir: withSeparator(dir), matcher: &Matcher{patterns: patterns}}, errors.Join(errs...)
+}
+
+// ParsePatterns reads patterns of the kind from the contents of an ignore file,
+// one per line. Like in .gitignore, blank lines and lines starting with "#" are
+// skipped. The source of each pattern is the name followed by its line number.
+// Invalid patterns are left out and returned as errors with the valid ones.
+func ParsePatterns(data []byte, kind PatternKind, name string) ([]IgnorePattern, []error) {
+	compiled, errs := parseLines(data, kind, name)
+	patterns := make([]IgnorePattern, 0, len(compiled))
+	for _, pattern := range compiled {
+		patterns = append(patterns, pattern.pattern)
+	}
+	return patterns, errs
+}
+
+// parseLines compiles the patterns of an ignore file, as described in ParsePatterns.
+func parseLines(data []byte, kind PatternKind, name string) ([]compiledPattern, []error) {
+	var (
+		patterns []compiledPattern
+		errs     []error
+	)
+	for i, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimRight(line, " \t\r")
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		source := fmt.Sprintf("%s:%d", name, i+1)
+		compiled, err := IgnorePattern{Pattern: line, Kind: kind, Source: source}.compile()
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", source, err))
+			continue
+		}
+		patterns = append(patterns, compiled)
+	}
+	return patterns, errs
+}
+
+// ignoreRules are the patterns that apply to a path while walking a source:
+// the patterns of the options and the ignore files of the directories above it.
+type ignoreRules struct {
+	root    string        // Source directory with a trailing separator, globs are relative to it
+	matcher *Matcher      // Patterns of the options
+	files   []*ignoreFile // Ignore files of the directories being walked, outermost first
+}
+
+// newIgnoreRules returns the rules for the paths of the source. Globs are
+// relative to the source if it is a directory, or to its parent directory
+// if it is a file.
+func newIgnoreRules(src string, matcher *Matcher) ignoreRules {
+	root := filepath.Dir(src)
+	if doesDirExist(src) {
+		root = src
+	}
+	return ignoreRules{root: withSeparator(root), matcher: matcher}
+}
+
+// decide returns the pattern that decides whether the path is ignored, or nil
+// if no pattern matches it. Patterns of deeper ignore files take precedence,
+// like in .gitignore, and all of them take precedence over the matcher.
+func (r *ignoreRules) decide(path string, isDir bool) *compiledPattern {
+	for i := len(r.files) - 1; i >= 0; i-- {
+		if pattern := r.files[i].matcher.last(path, r.files[i].dir, isDir); pattern != nil {
+			return pattern
+		}
+	}
+	return r.matcher.last(path, r.root, isDir)
+}
+
+// isIgnored reports whether the path is ignored.
+func (r *ignoreRules) isIgnored(path string, isDir bool) bool {
+	pattern := r.decide(path, isDir)
+	return pattern != nil && !pattern.negate
+}
+
+// enter reads the ignore file of the directory and applies it to the paths
+// inside until the returned function is called. Problems with the file are
+// returned as an error, the valid patterns apply anyway.
+func (r *ignoreRules) enter(dir string) (leave func(), err error) {
+	file, err := readIgnoreFile(dir)
+	if file == nil {
+		return func() {}, err
+	}
+	r.files = append(r.files, file)
+	return func() { r.files = r.files[:len(r.files)-1] }, err
+}
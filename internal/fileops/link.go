@@ -0,0 +1,179 @@
+package fileops
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// Link replaces the files at a specified destination with symbolic links to
+// the files of the source, the way GNU Stow does, and returns the plan it has
+// carried out. Like with Copy, the source is linked to the path with its name
+// inside the destination. Directories are created as needed, only files and
+// links of the source are linked.
+//
+// Files that already exist are skipped, unless Overwrite is set, and saved
+// to the Backup directory before they are replaced. Directories in the way
+// are never replaced. Links that already point to the source are left as
+// they are, so Link can be run again after files are added.
+func Link(src, dst string, opts Options) (*Plan, error) {
+	c := newCopier(opts, false)
+	return c.finishWalk(c.walkFiles(src, dst, c.linkFile))
+}
+
+// PlanLink walks the source and returns the steps Link would take
+// without touching the disk.
+func PlanLink(src, dst string, opts Options) (*Plan, error) {
+	c := newCopier(opts, true)
+	return c.finishWalk(c.walkFiles(src, dst, c.linkFile))
+}
+
+// Unlink reverses Link: links at the destination that point to the files
+// of the source are replaced with copies of those files. Everything else
+// at the destination is left as it is.
+func Unlink(src, dst string, opts Options) (*Plan, error) {
+	c := newCopier(opts, false)
+	return c.finishWalk(c.walkFiles(src, dst, c.unlinkFile))
+}
+
+// PlanUnlink walks the source and returns the steps Unlink would take
+// without touching the disk.
+func PlanUnlink(src, dst string, opts Options) (*Plan, error) {
+	c := newCopier(opts, true)
+	return c.finishWalk(c.walkFiles(src, dst, c.unlinkFile))
+}
+
+// finishWalk returns the plan together with the error of the walk or
+// the failures of its entries, the same way Copy does.
+func (c *copier) finishWalk(err error) (*Plan, error) {
+	errs := c.plan.Errors()
+	if len(errs) > 0 && !c.opts.ContinueOnError {
+		return c.plan, errs[0]
+	}
+	if err != nil {
+		return c.plan, err
+	}
+	if len(errs) > 0 {
+		return c.plan, errs
+	}
+	return c.plan, nil
+}
+
+// walkFiles calls fn for every file and link of the source with its absolute
+// path and the path it corresponds to inside the destination.
+func (c *copier) walkFiles(src, dst string, fn func(src, dst string) error) error {
+	src, err := filepath.Abs(src)
+	if err != nil {
+		return fmt.Errorf("get absolute path of %q: %w", src, err)
+	}
+	if _, err := os.Lstat(src); err != nil {
+		if os.IsNotExist(err) {
+			return fmt.Errorf("source %q does not exist", src)
+		}
+		return fmt.Errorf("stat file: %w", err)
+	}
+
+	root := filepath.Join(dst, filepath.Base(src))
+	return filepath.WalkDir(src, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return c.skipFailed(Entry{Src: path, IsDir: true}, fmt.Errorf("read source directory %q: %w", path, err))
+		}
+		if entry.IsDir() {
+			return nil
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return c.skipFailed(Entry{Src: path}, err)
+		}
+		return fn(path, filepath.Join(root, rel))
+	})
+}
+
+// linkFile replaces the destination with a link to the source file.
+func (c *copier) linkFile(src, dst string) error {
+	action := ActionCreate
+	reason := ""
+	if dstFileInfo, err := os.Lstat(dst); err == nil {
+		switch {
+		case isLinkTo(dst, src) || isSameFile(dst, src):
+			// The destination is already linked, either itself or through a linked directory
+			action = ActionUnchanged
+		case dstFileInfo.IsDir():
+			action, reason = ActionSkip, "directory exists"
+		case !c.opts.Overwrite:
+			action, reason = ActionSkip, "file exists"
+		default:
+			action = ActionOverwrite
+		}
+	} else if !os.IsNotExist(err) {
+		return c.skipFailed(Entry{Src: src, Dst: dst}, fmt.Errorf("stat file: %w", err))
+	}
+	c.plan.add(Entry{Action: action, Src: src, Dst: dst, Link: src, Reason: reason})
+
+	if c.dryRun || action == ActionSkip || action == ActionUnchanged {
+		return nil
+	}
+
+	// Create parent directory if it doesn't exist
+	index := len(c.plan.Entries) - 1
+	if err := os.MkdirAll(filepath.Dir(dst), 0740); err != nil {
+		return c.fail(index, fmt.Errorf("create directory %q: %w", filepath.Dir(dst), err))
+	}
+
+	if err := c.backup(src, dst); err != nil {
+		return c.fail(index, err)
+	}
+	if err := symlinkAtomic(src, dst); err != nil {
+		return c.fail(index, err)
+	}
+	return nil
+}
+
+// unlinkFile replaces the destination with a copy of the source file
+// if the destination is a link to it.
+func (c *copier) unlinkFile(src, dst string) error {
+	if !isLinkTo(dst, src) {
+		c.plan.add(Entry{Action: ActionSkip, Src: src, Dst: dst, Reason: "not linked"})
+		return nil
+	}
+
+	srcFileInfo, err := os.Lstat(src)
+	if err != nil {
+		return c.skipFailed(Entry{Src: src, Dst: dst}, fmt.Errorf("stat source file %q: %w", src, err))
+	}
+	c.plan.add(Entry{Action: ActionOverwrite, Src: src, Dst: dst, Size: srcFileInfo.Size()})
+
+	if c.dryRun {
+		return nil
+	}
+
+	// Links kept in the source are recreated, files are copied
+	index := len(c.plan.Entries) - 1
+	if srcFileInfo.Mode()&os.ModeSymlink != 0 {
+		target, err := os.Readlink(src)
+		if err != nil {
+			return c.fail(index, fmt.Errorf("read symlink %q: %w", src, err))
+		}
+		if err := symlinkAtomic(target, dst); err != nil {
+			return c.fail(index, err)
+		}
+		return nil
+	}
+	if err := writeFileAtomic(src, dst, srcFileInfo, c.opts.Metadata); err != nil {
+		return c.fail(index, err)
+	}
+	return nil
+}
+
+// isLinkTo reports whether the path is a symbolic link to the target.
+func isLinkTo(path, target string) bool {
+	link, err := os.Readlink(path)
+	if err != nil {
+		return false
+	}
+	if !filepath.IsAbs(link) {
+		link = filepath.Join(filepath.Dir(path), link)
+	}
+	return filepath.Clean(link) == target
+}
@@ -27,7 +27,8 @@ func ListFiles(dir string) ([]File, error) {
 	for _, entry := range entries {
 		entryPath := filepath.Join(dir, entry.Name())
 
-		if filepath.Base(entryPath) == ".git" {
+		// Skip the git directory and temporary files left behind by an interrupted copy
+		if filepath.Base(entryPath) == ".git" || (!entry.IsDir() && isTempFile(entry.Name())) {
 			continue
 		}
 
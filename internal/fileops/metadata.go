@@ -0,0 +1,45 @@
+package fileops
+
+import (
+	"fmt"
+	"os"
+)
+
+// dirMetadata is a directory whose metadata is applied after its contents are copied.
+type dirMetadata struct {
+	src  string
+	dst  string
+	info os.FileInfo
+}
+
+// preservedMode returns permission bits and special bits of the file mode.
+func preservedMode(info os.FileInfo) os.FileMode {
+	return info.Mode() & (os.ModePerm | os.ModeSetuid | os.ModeSetgid | os.ModeSticky)
+}
+
+// applyMetadata copies permissions, access and modification times
+// and extended attributes of the source to the destination.
+func applyMetadata(src, dst string, info os.FileInfo) error {
+	if err := os.Chmod(dst, preservedMode(info)); err != nil {
+		return fmt.Errorf("set mode of %q: %w", dst, err)
+	}
+	if err := copyXattrs(src, dst); err != nil {
+		return fmt.Errorf("copy extended attributes of %q: %w", src, err)
+	}
+	if err := os.Chtimes(dst, accessTime(info), info.ModTime()); err != nil {
+		return fmt.Errorf("set times of %q: %w", dst, err)
+	}
+	return nil
+}
+
+// applyDirMetadata applies metadata of the copied directories, children first,
+// so that setting times of a directory isn't undone by writing into it.
+func (c *copier) applyDirMetadata() error {
+	for i := len(c.dirs) - 1; i >= 0; i-- {
+		dir := c.dirs[i]
+		if err := applyMetadata(dir.src, dir.dst, dir.info); err != nil {
+			return err
+		}
+	}
+	return nil
+}
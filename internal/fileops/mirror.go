@@ -0,0 +1,61 @@
+package fileops
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// expect marks the destination path as one that belongs to the source.
+func (c *copier) expect(dst string) {
+	if c.opts.Mirror {
+		c.expected[dst] = struct{}{}
+	}
+}
+
+// keep marks the destination path and everything under it as belonging to
+// the source, e.g. when the source failed and its contents are unknown.
+func (c *copier) keep(dst string) {
+	if c.opts.Mirror && dst != "" {
+		c.kept[dst] = struct{}{}
+	}
+}
+
+// prune removes files and directories under the destination root that weren't
+// expected while walking the source, i.e. that were deleted from the source
+// or are ignored now. In dry-run mode it only records them in the plan.
+func (c *copier) prune(root string) error {
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		if _, ok := c.kept[path]; ok {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if _, ok := c.expected[path]; ok {
+			return nil
+		}
+
+		c.plan.add(Entry{Action: ActionDelete, Dst: path, IsDir: d.IsDir()})
+		if !c.dryRun {
+			if err := os.RemoveAll(path); err != nil {
+				return fmt.Errorf("remove %q: %w", path, err)
+			}
+		}
+		if d.IsDir() {
+			return filepath.SkipDir
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("prune %q: %w", root, err)
+	}
+	return nil
+}
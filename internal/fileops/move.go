@@ -0,0 +1,47 @@
+package fileops
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// Move moves a file or a directory into a specified destination directory,
+// which is created if it doesn't exist. If the destination is on another file
+// system, so that the source can't be renamed, it is copied with its links
+// and metadata, and removed once the copy is complete.
+//
+// Move refuses to replace anything that already exists in the destination.
+func Move(src, dst string) error {
+	dst = filepath.Join(dst, filepath.Base(src))
+	if _, err := os.Lstat(dst); err == nil {
+		return fmt.Errorf("destination %q already exists", dst)
+	} else if !os.IsNotExist(err) {
+		return fmt.Errorf("stat file: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(dst), 0740); err != nil {
+		return fmt.Errorf("create directory %q: %w", filepath.Dir(dst), err)
+	}
+	if err := os.Rename(src, dst); err == nil {
+		return nil
+	} else if !isCrossDevice(err) {
+		return fmt.Errorf("move %q: %w", src, err)
+	}
+
+	_, err := Copy(src, filepath.Dir(dst), Options{CreateDst: true, Symlinks: SymlinkPreserve, Metadata: true})
+	if err != nil {
+		// Nothing has been moved yet, so the partial copy can go
+		os.RemoveAll(dst)
+		return fmt.Errorf("copy %q: %w", src, err)
+	}
+	if err := os.RemoveAll(src); err != nil {
+		// A file is either removed or left intact, while a directory may be
+		// partly removed, so then the copy is the only complete one
+		if info, statErr := os.Lstat(src); statErr == nil && !info.IsDir() {
+			os.Remove(dst)
+		}
+		return fmt.Errorf("remove %q after copying it: %w", src, err)
+	}
+	return nil
+}
@@ -0,0 +1,170 @@
+package fileops
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// Action describes what the collector does with a single source entry.
+type Action int
+
+const (
+	ActionCreate    Action = iota // Destination doesn't exist and will be created
+	ActionOverwrite               // Destination exists and will be replaced
+	ActionSkip                    // Destination exists and overwrite is disabled
+	ActionUnchanged               // Destination is already up to date
+	ActionIgnore                  // Source matches one of the ignore patterns
+	ActionDelete                  // Destination isn't in the source anymore and will be removed
+)
+
+// String returns a short lowercase name of the action.
+func (a Action) String() string {
+	switch a {
+	case ActionCreate:
+		return "create"
+	case ActionOverwrite:
+		return "overwrite"
+	case ActionSkip:
+		return "skip"
+	case ActionUnchanged:
+		return "unchanged"
+	case ActionIgnore:
+		return "ignore"
+	case ActionDelete:
+		return "delete"
+	}
+	return "unknown"
+}
+
+// Entry is a single step of a copy plan.
+type Entry struct {
+	Action Action
+	Src    string
+	Dst    string
+	IsDir  bool
+	Size   int64      // Size of the source file
+	Link   string     // Target of a symbolic link recreated in the destination
+	Reason string     // Why the entry is skipped, if it isn't obvious from the action
+	Err    *FileError // Failure to carry out the entry, nil if it has succeeded
+}
+
+// Reasons an entry is skipped for that Copy and Explain share.
+const (
+	reasonExcluded  = "excluded"
+	reasonTemporary = "temporary file"
+	reasonSymlink   = "symlink"
+	reasonDangling  = "dangling symlink"
+	reasonLoop      = "symlink loop"
+)
+
+// Plan is a list of steps required to copy a source to the destination.
+type Plan struct {
+	Entries  []Entry
+	Warnings []string // Problems that don't stop the copy but deserve attention
+}
+
+// Count returns the number of entries with the given action that haven't failed.
+func (p *Plan) Count(action Action) int {
+	n := 0
+	for _, entry := range p.Entries {
+		if entry.Action == action && entry.Err == nil {
+			n++
+		}
+	}
+	return n
+}
+
+// Bytes returns the total size of files that are copied without failure.
+func (p *Plan) Bytes() int64 {
+	var n int64
+	for _, entry := range p.Entries {
+		if (entry.Action == ActionCreate || entry.Action == ActionOverwrite) && entry.Err == nil {
+			n += entry.Size
+		}
+	}
+	return n
+}
+
+// Errors returns failures of the entries in the order they were planned.
+func (p *Plan) Errors() Errors {
+	var errs Errors
+	for _, entry := range p.Entries {
+		if entry.Err != nil {
+			errs = append(errs, entry.Err)
+		}
+	}
+	return errs
+}
+
+// Collisions returns pairs of files from different sources that are written
+// to the same destination path, or where one file is written in place of
+// a directory the other one needs. Paths are compared case-insensitively,
+// so that the collection can be moved to a case-insensitive file system.
+func (p *Plan) Collisions() [][2]Entry {
+	var collisions [][2]Entry
+	written := make(map[string]Entry) // Files by their destination
+	parents := make(map[string]Entry) // First file inside each destination directory
+	for _, entry := range p.Entries {
+		if entry.IsDir || entry.Err != nil {
+			continue
+		}
+		if entry.Action != ActionCreate && entry.Action != ActionOverwrite && entry.Action != ActionUnchanged {
+			continue
+		}
+		key := strings.ToLower(entry.Dst)
+		if first, ok := written[key]; ok {
+			if first.Src != entry.Src {
+				collisions = append(collisions, [2]Entry{first, entry})
+			}
+			continue
+		}
+		if first, ok := parents[key]; ok {
+			collisions = append(collisions, [2]Entry{first, entry})
+			continue
+		}
+		written[key] = entry
+		for dir := filepath.Dir(key); dir != filepath.Dir(dir); dir = filepath.Dir(dir) {
+			if first, ok := written[dir]; ok {
+				collisions = append(collisions, [2]Entry{first, entry})
+				break
+			}
+			if _, ok := parents[dir]; !ok {
+				parents[dir] = entry
+			}
+		}
+	}
+	return collisions
+}
+
+// Merge appends entries of the other plan to the plan.
+func (p *Plan) Merge(other *Plan) {
+	if other == nil {
+		return
+	}
+	p.Entries = append(p.Entries, other.Entries...)
+	p.Warnings = append(p.Warnings, other.Warnings...)
+}
+
+// add appends an entry to the plan.
+func (p *Plan) add(entry Entry) {
+	p.Entries = append(p.Entries, entry)
+}
+
+// warn adds a warning to the plan.
+func (p *Plan) warn(format string, args ...any) {
+	p.Warnings = append(p.Warnings, fmt.Sprintf(format, args...))
+}
+
+// PlanCopy walks the source and returns the steps Copy would take
+// without touching the disk.
+func PlanCopy(src, dst string, opts Options) (*Plan, error) {
+	plans, errs := PlanCopyAll([]Source{{Src: src, Dst: dst, Ignore: opts.Ignore, Symlinks: opts.Symlinks}}, opts)
+	return plans[0], errs[0]
+}
+
+// PlanCopyAll walks the sources and returns the steps CopyAll would take
+// without touching the disk.
+func PlanCopyAll(sources []Source, opts Options) ([]*Plan, []error) {
+	return copyAll(sources, opts, true)
+}
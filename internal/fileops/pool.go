@@ -0,0 +1,71 @@
+package fileops
+
+import "sync"
+
+// pool runs tasks for plan entries on a bounded number of goroutines and
+// keeps their outcomes by the entries, so that the failures are reported
+// in the order the entries were planned, no matter how the tasks were scheduled.
+type pool struct {
+	sem      chan struct{}
+	wg       sync.WaitGroup
+	mu       sync.Mutex
+	outcomes map[entryRef]outcome
+	failures int
+}
+
+// entryRef identifies an entry of a plan.
+type entryRef struct {
+	plan  *Plan
+	index int
+}
+
+// outcome is the action a task has taken for its entry and the error it has returned.
+type outcome struct {
+	action Action
+	err    error
+}
+
+// newPool returns a pool running at most jobs tasks at a time.
+func newPool(jobs int) *pool {
+	return &pool{sem: make(chan struct{}, jobs), outcomes: make(map[entryRef]outcome)}
+}
+
+// run starts the task for the entry with the given index as soon as a worker
+// is available. The action the task returns replaces the planned one.
+func (p *pool) run(plan *Plan, index int, task func() (Action, error)) {
+	p.sem <- struct{}{}
+	p.wg.Add(1)
+	go func() {
+		defer func() {
+			<-p.sem
+			p.wg.Done()
+		}()
+		action, err := task()
+		p.mu.Lock()
+		p.outcomes[entryRef{plan, index}] = outcome{action, err}
+		if err != nil {
+			p.failures++
+		}
+		p.mu.Unlock()
+	}()
+}
+
+// failed reports whether any of the finished tasks has returned an error.
+func (p *pool) failed() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.failures > 0
+}
+
+// wait waits for all tasks to finish and records their outcomes on the entries.
+func (p *pool) wait() {
+	p.wg.Wait()
+	for ref, outcome := range p.outcomes {
+		entry := &ref.plan.Entries[ref.index]
+		entry.Action = outcome.action
+		if outcome.err != nil {
+			entry.Err = NewFileError(entry.Src, outcome.err)
+		}
+	}
+	clear(p.outcomes)
+}
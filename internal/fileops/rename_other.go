@@ -0,0 +1,14 @@
+//go:build !windows
+
+package fileops
+
+import (
+	"errors"
+	"syscall"
+)
+
+// isCrossDevice reports whether a rename failed because the source
+// and the destination are on different file systems.
+func isCrossDevice(err error) bool {
+	return errors.Is(err, syscall.EXDEV)
+}
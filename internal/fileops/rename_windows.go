@@ -0,0 +1,15 @@
+package fileops
+
+import (
+	"errors"
+	"syscall"
+)
+
+// errorNotSameDevice is the ERROR_NOT_SAME_DEVICE error code of Windows.
+const errorNotSameDevice syscall.Errno = 17
+
+// isCrossDevice reports whether a rename failed because the source
+// and the destination are on different volumes.
+func isCrossDevice(err error) bool {
+	return errors.Is(err, errorNotSameDevice)
+}
@@ -0,0 +1,153 @@
+package fileops
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// fileRule decides whether a file is ignored by its attributes rather than by its path.
+type fileRule func(path string, info os.FileInfo) bool
+
+// compileRule translates a pattern of a rule kind into a rule.
+func compileRule(kind PatternKind, pattern string) (fileRule, error) {
+	switch kind {
+	case PatternSize:
+		limit, err := parseSize(pattern)
+		if err != nil {
+			return nil, err
+		}
+		return func(path string, info os.FileInfo) bool {
+			return info.Size() > limit
+		}, nil
+	case PatternAge:
+		age, err := ParseAge(pattern)
+		if err != nil {
+			return nil, err
+		}
+		// Age is measured from the moment the rule is compiled, so that
+		// every file of a collection is compared with the same time
+		cutoff := time.Now().Add(-age)
+		return func(path string, info os.FileInfo) bool {
+			return info.ModTime().Before(cutoff)
+		}, nil
+	case PatternType:
+		return compileTypeRule(pattern)
+	}
+	return nil, fmt.Errorf("pattern kind %s is not a rule", kind)
+}
+
+// compileTypeRule returns a rule matching files of the named type.
+func compileTypeRule(name string) (fileRule, error) {
+	switch name {
+	case "socket":
+		return func(path string, info os.FileInfo) bool {
+			return info.Mode()&os.ModeSocket != 0
+		}, nil
+	case "fifo":
+		return func(path string, info os.FileInfo) bool {
+			return info.Mode()&os.ModeNamedPipe != 0
+		}, nil
+	case "device":
+		return func(path string, info os.FileInfo) bool {
+			return info.Mode()&os.ModeDevice != 0
+		}, nil
+	case "binary":
+		return isBinaryExecutable, nil
+	}
+	return nil, fmt.Errorf("unknown file type %q, expected socket, fifo, device or binary", name)
+}
+
+// executableMagic are the headers of executable formats: ELF, Mach-O
+// in both byte orders, universal Mach-O and PE.
+var executableMagic = [][]byte{
+	[]byte("\x7fELF"),
+	{0xfe, 0xed, 0xfa, 0xce},
+	{0xfe, 0xed, 0xfa, 0xcf},
+	{0xce, 0xfa, 0xed, 0xfe},
+	{0xcf, 0xfa, 0xed, 0xfe},
+	{0xca, 0xfe, 0xba, 0xbe},
+	[]byte("MZ"),
+}
+
+// isBinaryExecutable reports whether the file is a compiled executable.
+// Only executable files are read, so scripts and other text files with
+// the executable bit are told apart from binaries by their header.
+func isBinaryExecutable(path string, info os.FileInfo) bool {
+	if !info.Mode().IsRegular() {
+		return false
+	}
+	// Windows has no executable bit, so executables are known by their extension
+	if runtime.GOOS == "windows" {
+		ext := strings.ToLower(filepath.Ext(path))
+		if ext != ".exe" && ext != ".dll" {
+			return false
+		}
+	} else if info.Mode().Perm()&0111 == 0 {
+		return false
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+	header := make([]byte, 4)
+	n, _ := io.ReadFull(f, header)
+	for _, magic := range executableMagic {
+		if bytes.HasPrefix(header[:n], magic) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseSize parses a size such as "512", "100KB", "5M" or "1.5GB".
+// Units are powers of 1024 and the trailing "B" is optional.
+func parseSize(s string) (int64, error) {
+	number := strings.TrimSuffix(strings.ToUpper(strings.TrimSpace(s)), "B")
+	multiplier := int64(1)
+	if number != "" {
+		if i := strings.IndexByte("KMGT", number[len(number)-1]); i >= 0 {
+			multiplier = 1 << (10 * (i + 1))
+			number = number[:len(number)-1]
+		}
+	}
+	value, err := strconv.ParseFloat(strings.TrimSpace(number), 64)
+	if err != nil || math.IsNaN(value) || value < 0 {
+		return 0, fmt.Errorf("invalid size %q, expected a number with an optional unit such as 100KB or 5MB", s)
+	}
+	// Larger sizes, including infinity, would overflow when converted
+	size := value * float64(multiplier)
+	if size >= math.MaxInt64 {
+		return 0, fmt.Errorf("size %q is too large", s)
+	}
+	return int64(size), nil
+}
+
+// ParseAge parses an age such as "30d", "2w" or "2y". Besides days, weeks
+// and years of 365 days, it accepts the units of time.ParseDuration.
+func ParseAge(s string) (time.Duration, error) {
+	s = strings.TrimSpace(s)
+	units := map[byte]time.Duration{'d': 24 * time.Hour, 'w': 7 * 24 * time.Hour, 'y': 365 * 24 * time.Hour}
+	if s != "" {
+		if unit, ok := units[s[len(s)-1]]; ok {
+			n, err := strconv.Atoi(s[:len(s)-1])
+			if err == nil && n > 0 && n <= int(math.MaxInt64/unit) {
+				return time.Duration(n) * unit, nil
+			}
+		}
+	}
+	age, err := time.ParseDuration(s)
+	if err != nil || age <= 0 {
+		return 0, fmt.Errorf("invalid age %q, expected a positive duration such as 30d, 2w or 2y", s)
+	}
+	return age, nil
+}
@@ -0,0 +1,153 @@
+package fileops
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// SymlinkPolicy defines how the collector treats symbolic links.
+type SymlinkPolicy int
+
+const (
+	SymlinkFollow   SymlinkPolicy = iota // Copy the file or directory the link points to
+	SymlinkPreserve                      // Recreate the link itself in the destination
+	SymlinkSkip                          // Leave the link out of the destination
+)
+
+// String returns the name of the policy as accepted by ParseSymlinkPolicy.
+func (p SymlinkPolicy) String() string {
+	switch p {
+	case SymlinkFollow:
+		return "follow"
+	case SymlinkPreserve:
+		return "preserve"
+	case SymlinkSkip:
+		return "skip"
+	}
+	return "unknown"
+}
+
+// ParseSymlinkPolicy returns the policy with the given name.
+func ParseSymlinkPolicy(name string) (SymlinkPolicy, error) {
+	switch name {
+	case "follow":
+		return SymlinkFollow, nil
+	case "preserve":
+		return SymlinkPreserve, nil
+	case "skip":
+		return SymlinkSkip, nil
+	}
+	return 0, fmt.Errorf("unknown symlink policy %q, expected follow, preserve or skip", name)
+}
+
+// copySymlink handles a symbolic link according to the symlink policy.
+// The dst is the full destination path of the link.
+func (c *copier) copySymlink(src, dst string) error {
+	// A link made by Link already points to the destination, which must not be replaced with a link to itself
+	if isLinkTo(src, dst) {
+		c.plan.add(Entry{Action: ActionUnchanged, Src: src, Dst: dst, Reason: "linked"})
+		c.expect(dst)
+		return nil
+	}
+
+	info, reason, err := c.followSymlink(src)
+	switch {
+	case err != nil:
+		return c.skipFailed(Entry{Src: src, Dst: dst}, err)
+	case reason == reasonDangling:
+		c.plan.add(Entry{Action: ActionSkip, Src: src, Dst: dst, Reason: reason})
+		c.plan.warn("skipped dangling symlink %s", src)
+		return nil
+	case reason == reasonLoop:
+		c.plan.add(Entry{Action: ActionSkip, Src: src, Dst: dst, IsDir: true, Reason: reason})
+		c.plan.warn("skipped symlink %s that points to its own parent directory", src)
+		return nil
+	case reason != "":
+		c.plan.add(Entry{Action: ActionSkip, Src: src, Dst: dst, Reason: reason})
+		return nil
+	case info == nil:
+		return c.preserveSymlink(src, dst)
+	case !info.IsDir():
+		return c.copyRegularFile(src, dst, info)
+	}
+	return c.copyDirectory(src, filepath.Dir(dst))
+}
+
+// followSymlink applies the symlink policy to the link. It returns the file
+// info of the target if the link is followed, nil if the link is preserved,
+// or the reason the link is skipped for.
+func (c *copier) followSymlink(src string) (os.FileInfo, string, error) {
+	switch c.opts.Symlinks {
+	case SymlinkSkip:
+		return nil, reasonSymlink, nil
+	case SymlinkPreserve:
+		return nil, "", nil
+	}
+
+	// Follow the link, skipping it if the target is gone
+	info, err := os.Stat(src)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, reasonDangling, nil
+		}
+		return nil, "", fmt.Errorf("stat symlink target %q: %w", src, err)
+	}
+
+	// Refuse to descend into a directory that is already being copied
+	if info.IsDir() {
+		for _, ancestor := range c.ancestors {
+			if os.SameFile(ancestor, info) {
+				return nil, reasonLoop, nil
+			}
+		}
+	}
+	return info, "", nil
+}
+
+// preserveSymlink recreates the link at the destination with the same target.
+func (c *copier) preserveSymlink(src, dst string) error {
+	target, err := os.Readlink(src)
+	if err != nil {
+		return c.skipFailed(Entry{Src: src, Dst: dst}, fmt.Errorf("read symlink %q: %w", src, err))
+	}
+
+	action := ActionCreate
+	if dstFileInfo, err := os.Lstat(dst); err == nil {
+		action = ActionOverwrite
+		if dstFileInfo.Mode()&os.ModeSymlink != 0 {
+			if dstTarget, err := os.Readlink(dst); err == nil && dstTarget == target {
+				action = ActionUnchanged
+			}
+		}
+		if action == ActionOverwrite && !c.opts.Overwrite {
+			action = ActionSkip
+		}
+		// Files of a directory would be lost if the link replaced it without a backup
+		if action == ActionOverwrite && dstFileInfo.IsDir() && c.opts.Backup == "" && !isEmptyDir(dst) {
+			return c.skipFailed(Entry{Src: src, Dst: dst, Link: target}, fmt.Errorf("replace directory %q with a symlink: directory is not empty", dst))
+		}
+	} else if !os.IsNotExist(err) {
+		return c.skipFailed(Entry{Src: src, Dst: dst}, fmt.Errorf("stat file: %w", err))
+	}
+	c.plan.add(Entry{Action: action, Src: src, Dst: dst, Link: target})
+	c.expect(dst)
+
+	if c.dryRun || action == ActionSkip || action == ActionUnchanged {
+		return nil
+	}
+
+	// Create parent directory if it doesn't exist
+	index := len(c.plan.Entries) - 1
+	if err := os.MkdirAll(filepath.Dir(dst), 0740); err != nil {
+		return c.fail(index, fmt.Errorf("create directory %q: %w", filepath.Dir(dst), err))
+	}
+
+	if err := c.backup(src, dst); err != nil {
+		return c.fail(index, err)
+	}
+	if err := symlinkAtomic(target, dst); err != nil {
+		return c.fail(index, err)
+	}
+	return nil
+}
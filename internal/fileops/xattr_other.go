@@ -0,0 +1,8 @@
+//go:build !linux
+
+package fileops
+
+// copyXattrs does nothing, as extended attributes are only preserved on Linux.
+func copyXattrs(src, dst string) error {
+	return nil
+}
@@ -1,10 +1,13 @@
 package tui
 
 import (
+	"errors"
 	"fmt"
+	"strings"
 
 	"github.com/charmbracelet/bubbles/key"
 	tea "github.com/charmbracelet/bubbletea"
+	"github.com/chtozamm/dotfiles-collector/internal/app"
 	"github.com/chtozamm/dotfiles-collector/internal/fileops"
 )
 
@@ -16,17 +19,40 @@ func (m *model) moveCursor(direction int) {
 	}
 }
 
+// collectOptions are the options of collection started from the terminal interface.
+// Failed files don't stop the collection, they are listed once it's done.
+var collectOptions = app.CollectOptions{ContinueOnError: true}
+
 func (m *model) handleCollectFiles() {
 	m.lastView = m.view
-	err := m.app.CopyFiles()
-	if err != nil {
-		if err.Error() == "no paths found in database" {
+	pending, err := m.app.PlanCopyFiles(collectOptions)
+	var errs fileops.Errors
+	if err != nil && !errors.As(err, &errs) {
+		if errors.Is(err, app.ErrNoPaths) {
 			m.msg = "No paths found to collect files from"
 		} else {
-			m.msg = fmt.Sprintf("Failed to collect files: %v", err)
+			m.msg = fmt.Sprintf("Failed to plan collection: %v", err)
 		}
-	} else {
+		m.view = infoMessageView
+		return
+	}
+	m.pending = pending
+	m.view = confirmCollectView
+}
+
+func (m *model) handleConfirmCollect() {
+	m.pending = nil
+	result, err := m.app.CopyFiles(collectOptions)
+	var errs fileops.Errors
+	switch {
+	case err != nil && !errors.As(err, &errs):
+		m.msg = fmt.Sprintf("Failed to collect files: %v", err)
+	case len(errs) > 0:
+		m.msg = "Collected files with errors"
+		m.result = result
+	default:
 		m.msg = "Successfully collected files"
+		m.result = result
 	}
 	m.view = infoMessageView
 }
@@ -39,6 +65,8 @@ func (m *model) handleEnter() {
 		m.handlePathsView()
 	case manageIgnorePatternsView:
 		m.handleIgnorePatternsView()
+	case confirmCollectView:
+		m.handleConfirmCollect()
 	case removePathsView:
 		fallthrough
 	case removeIgnorePatternsView:
@@ -122,6 +150,8 @@ func (m *model) handleDelete() {
 		m.handleDeleteIgnorePattern()
 	case removeIgnorePatternsView:
 		m.handleDeleteIgnorePatterns()
+	case presetsView:
+		m.handleRemovePreset()
 	}
 
 	if len(m.options[m.view]) == 0 {
@@ -131,6 +161,56 @@ func (m *model) handleDelete() {
 	}
 }
 
+func (m *model) handleToggle() {
+	switch m.view {
+	case listPathsView:
+		m.handleTogglePath()
+	case listIgnorePatternsView:
+		m.handleToggleIgnorePattern()
+	}
+}
+
+func (m *model) handleTogglePath() {
+	paths, err := m.app.GetCollectPaths()
+	if err != nil {
+		m.msg = fmt.Sprintf("Failed to get paths: %v", err)
+		m.lastView = m.view
+		m.view = infoMessageView
+		return
+	}
+	if m.cursors[m.view] >= len(paths) {
+		return
+	}
+
+	path := paths[m.cursors[m.view]]
+	if err := m.app.SetCollectPathEnabled(path.Path, !path.Enabled); err != nil {
+		m.msg = fmt.Sprintf("Failed to update path: %v", err)
+		m.lastView = m.view
+		m.view = infoMessageView
+	}
+}
+
+func (m *model) handleToggleIgnorePattern() {
+	patterns, err := m.app.GetIgnorePatterns()
+	if err != nil {
+		m.msg = fmt.Sprintf("Failed to get ignore patterns: %v", err)
+		m.lastView = m.view
+		m.view = infoMessageView
+		return
+	}
+	patterns = globalPatterns(patterns)
+	if m.cursors[m.view] >= len(patterns) {
+		return
+	}
+
+	pattern := patterns[m.cursors[m.view]]
+	if err := m.app.SetIgnorePatternEnabled(pattern.Pattern, pattern.Kind, "", !pattern.Enabled); err != nil {
+		m.msg = fmt.Sprintf("Failed to update ignore pattern: %v", err)
+		m.lastView = m.view
+		m.view = infoMessageView
+	}
+}
+
 func (m *model) handleDeleteCollectedFile() {
 	if len(m.options[m.view]) == 0 {
 		return
@@ -170,6 +250,8 @@ func (m *model) handleAdd() {
 	case listIgnorePatternsView:
 		m.lastView = m.view
 		m.view = addIgnorePatternView
+	case presetsView:
+		m.handleAddPreset()
 	}
 }
 
@@ -178,6 +260,10 @@ func (m *model) handleBackspace() {
 	switch m.view {
 	case infoMessageView:
 		m.msg = ""
+		m.result = nil
+		m.view = m.lastView
+	case confirmCollectView:
+		m.pending = nil
 		m.view = m.lastView
 	case listPathsView:
 		m.view = managePathsView
@@ -195,6 +281,8 @@ func (m *model) handleBackspace() {
 	case removeIgnorePatternsView:
 		m.choices[m.view] = map[string]bool{}
 		m.view = manageIgnorePatternsView
+	case presetsView:
+		m.view = manageIgnorePatternsView
 	default:
 		m.view = initialView
 	}
@@ -250,6 +338,34 @@ func (m *model) handleIgnorePatternsView() {
 		m.view = addIgnorePatternView
 	case 2:
 		m.view = removeIgnorePatternsView
+	case 3:
+		m.view = presetsView
+	}
+}
+
+func (m *model) handleAddPreset() {
+	if len(m.options[m.view]) == 0 {
+		return
+	}
+
+	_, err := m.app.AddPreset(m.options[m.view][m.cursors[m.view]])
+	if err != nil {
+		m.msg = fmt.Sprintf("Failed to add preset: %v", err)
+		m.lastView = m.view
+		m.view = infoMessageView
+	}
+}
+
+func (m *model) handleRemovePreset() {
+	if len(m.options[m.view]) == 0 {
+		return
+	}
+
+	err := m.app.RemovePreset(m.options[m.view][m.cursors[m.view]])
+	if err != nil {
+		m.msg = fmt.Sprintf("Failed to remove preset: %v", err)
+		m.lastView = m.view
+		m.view = infoMessageView
 	}
 }
 
@@ -258,7 +374,20 @@ func (m *model) handleDeleteIgnorePattern() {
 		return
 	}
 
-	err := m.app.RemoveIgnorePattern(m.options[m.view][m.cursors[m.view]])
+	patterns, err := m.app.GetIgnorePatterns()
+	if err != nil {
+		m.msg = fmt.Sprintf("Failed to get ignore patterns: %v", err)
+		m.lastView = m.view
+		m.view = infoMessageView
+		return
+	}
+	global := globalPatterns(patterns)
+	if m.cursors[m.view] >= len(global) {
+		return
+	}
+
+	pattern := global[m.cursors[m.view]]
+	err = m.app.RemoveIgnorePattern(pattern.Pattern, pattern.Kind, "")
 	if err != nil {
 		m.msg = fmt.Sprintf("Failed to remove ignore pattern: %v", err)
 		m.lastView = m.view
@@ -266,14 +395,14 @@ func (m *model) handleDeleteIgnorePattern() {
 		return
 	}
 
-	patterns, err := m.app.GetIgnorePatterns()
+	patterns, err = m.app.GetIgnorePatterns()
 	if err != nil {
 		m.msg = fmt.Sprintf("Failed to get ignore patterns: %v", err)
 		m.lastView = m.view
 		m.view = infoMessageView
 		return
 	}
-	m.options[m.view] = patterns
+	m.options[m.view] = patternKeys(patterns)
 }
 
 func (m *model) handleDeleteIgnorePatterns() {
@@ -281,16 +410,24 @@ func (m *model) handleDeleteIgnorePatterns() {
 		return
 	}
 
-	patternsToDelete := []string{}
+	patterns, err := m.app.GetIgnorePatterns()
+	if err != nil {
+		m.msg = fmt.Sprintf("Failed to get ignore patterns: %v", err)
+		m.lastView = m.view
+		m.view = infoMessageView
+		return
+	}
 
-	for pattern, marked := range m.choices[m.view] {
-		if !marked {
+	patternsToDelete := []app.IgnorePattern{}
+
+	for _, pattern := range globalPatterns(patterns) {
+		if !m.choices[m.view][patternKey(pattern)] {
 			continue
 		}
 		patternsToDelete = append(patternsToDelete, pattern)
 	}
 
-	err := m.app.RemoveIgnorePatterns(patternsToDelete)
+	err = m.app.RemoveIgnorePatterns(patternsToDelete)
 	if err != nil {
 		m.msg = fmt.Sprintf("Failed to remove ignore patterns: %v", err)
 		m.lastView = m.view
@@ -298,17 +435,44 @@ func (m *model) handleDeleteIgnorePatterns() {
 		return
 	}
 
-	patterns, err := m.app.GetIgnorePatterns()
+	patterns, err = m.app.GetIgnorePatterns()
 	if err != nil {
 		m.msg = fmt.Sprintf("Failed to get ignore patterns: %v", err)
 		m.lastView = m.view
 		m.view = infoMessageView
 		return
 	}
-	m.options[m.view] = patterns
+	m.options[m.view] = patternKeys(patterns)
 	m.choices[m.view] = map[string]bool{}
 }
 
+// globalPatterns returns the patterns that apply to every source.
+// Patterns scoped to a source are shown under that source instead.
+func globalPatterns(patterns []app.IgnorePattern) []app.IgnorePattern {
+	global := make([]app.IgnorePattern, 0, len(patterns))
+	for _, pattern := range patterns {
+		if pattern.SourceID == 0 {
+			global = append(global, pattern)
+		}
+	}
+	return global
+}
+
+// patternKeys returns the keys of the global patterns, which identify them in the options.
+func patternKeys(patterns []app.IgnorePattern) []string {
+	keys := make([]string, 0, len(patterns))
+	for _, pattern := range globalPatterns(patterns) {
+		keys = append(keys, patternKey(pattern))
+	}
+	return keys
+}
+
+// patternKey returns the kind and the text of the pattern, since the same
+// text can be added as patterns of different kinds.
+func patternKey(pattern app.IgnorePattern) string {
+	return pattern.Kind.String() + ":" + pattern.Pattern
+}
+
 func handleInput(m *model, msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	if key.Matches(msg, m.keymap.inputCancel) {
 		m.textInput.Reset()
@@ -320,7 +484,7 @@ func handleInput(m *model, msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		switch m.view {
 		case addPathView:
 			m.lastView = managePathsView
-			err := m.app.AddCollectPath(m.textInput.Value(), "")
+			warnings, err := m.app.AddCollectPath(m.textInput.Value(), "", "")
 			if err != nil {
 				m.textInput.Reset()
 				m.msg = fmt.Sprintf("Failed to add path: %v", err)
@@ -346,10 +510,17 @@ func handleInput(m *model, msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 			m.options[listPathsView] = filenames
 			m.view = listPathsView
 
+			// Let the user know about the parts of the path that won't be collected
+			if len(warnings) > 0 {
+				m.lastView = listPathsView
+				m.msg = "Added path with warnings:\n\n  " + m.marginLeft + strings.Join(warnings, "\n  "+m.marginLeft)
+				m.view = infoMessageView
+			}
+
 			return m, nil
 		case addIgnorePatternView:
 			m.lastView = manageIgnorePatternsView
-			err := m.app.AddIgnorePattern(m.textInput.Value())
+			err := m.app.AddIgnorePattern(m.textInput.Value(), fileops.PatternRegex, "")
 			if err != nil {
 				m.textInput.Reset()
 				m.msg = fmt.Sprintf("Failed to add ignore pattern: %v", err)
@@ -367,7 +538,7 @@ func handleInput(m *model, msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 				return m, nil
 			}
 
-			m.options[listIgnorePatternsView] = patterns
+			m.options[listIgnorePatternsView] = patternKeys(patterns)
 			m.view = listIgnorePatternsView
 
 			return m, nil
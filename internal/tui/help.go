@@ -15,7 +15,7 @@ func (m model) renderHelpView() string {
 
 	switch m.view {
 	case infoMessageView:
-		if m.msg == "Successfully collected files" {
+		if m.result != nil {
 			firstRow = []key.Binding{
 				m.keymap.viewCollectedFiles,
 			}
@@ -29,6 +29,14 @@ func (m model) renderHelpView() string {
 				m.keymap.quit,
 			}
 		}
+	case confirmCollectView:
+		firstRow = []key.Binding{
+			m.keymap.confirm,
+		}
+		secondRow = []key.Binding{
+			m.keymap.back,
+			m.keymap.quit,
+		}
 	case initialView:
 		firstRow = []key.Binding{
 			m.keymap.up,
@@ -56,6 +64,7 @@ func (m model) renderHelpView() string {
 			m.keymap.add,
 			// m.keymap.edit,
 			m.keymap.delete,
+			m.keymap.toggle,
 			m.keymap.collectFiles,
 		}
 		secondRow = []key.Binding{
@@ -72,6 +81,7 @@ func (m model) renderHelpView() string {
 			m.keymap.add,
 			// m.keymap.edit,
 			m.keymap.delete,
+			m.keymap.toggle,
 		}
 		secondRow = []key.Binding{
 			m.keymap.up,
@@ -82,6 +92,19 @@ func (m model) renderHelpView() string {
 			m.keymap.back,
 			m.keymap.quit,
 		}
+	case presetsView:
+		firstRow = []key.Binding{
+			m.keymap.add,
+			m.keymap.delete,
+		}
+		secondRow = []key.Binding{
+			m.keymap.up,
+			m.keymap.down,
+		}
+		thirdRow = []key.Binding{
+			m.keymap.back,
+			m.keymap.quit,
+		}
 	case addPathView:
 		fallthrough
 	case addIgnorePatternView:
@@ -10,6 +10,7 @@ type keymap struct {
 	// search          key.Binding
 	viewCollectedFiles key.Binding
 	collectFiles       key.Binding
+	confirm            key.Binding
 	selectionToggle    key.Binding
 	selectionCancel    key.Binding
 	inputSubmit        key.Binding
@@ -18,6 +19,7 @@ type keymap struct {
 	add                key.Binding
 	edit               key.Binding
 	delete             key.Binding
+	toggle             key.Binding
 	expandDir          key.Binding
 }
 
@@ -58,6 +60,10 @@ var keymaps = keymap{
 		key.WithKeys("c"),
 		key.WithHelp("c", "collect files"),
 	),
+	confirm: key.NewBinding(
+		key.WithKeys("y"),
+		key.WithHelp("y/enter", "confirm"),
+	),
 	selectionToggle: key.NewBinding(
 		key.WithKeys("enter", " "),
 		key.WithHelp("enter/space", "select/deselect"),
@@ -82,6 +88,10 @@ var keymaps = keymap{
 		key.WithKeys("d"),
 		key.WithHelp("d", "delete"),
 	),
+	toggle: key.NewBinding(
+		key.WithKeys("t"),
+		key.WithHelp("t", "enable/disable"),
+	),
 	expandDir: key.NewBinding(
 		key.WithKeys("enter", " "),
 		key.WithHelp("enter/space", "expand directory"),
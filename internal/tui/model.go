@@ -27,6 +27,7 @@ const (
 	listCollectedFilesView
 
 	infoMessageView
+	confirmCollectView
 
 	// promptConfirmAdd
 	// promptConfirmEdit
@@ -42,6 +43,7 @@ const (
 	listIgnorePatternsView
 	addIgnorePatternView
 	removeIgnorePatternsView
+	presetsView
 )
 
 type model struct {
@@ -53,6 +55,8 @@ type model struct {
 	cursors    map[viewState]int
 	textInput  textinput.Model
 	msg        string
+	pending    *app.CollectResult // Planned collection waiting for confirmation
+	result     *app.CollectResult // Result of the last collection shown in the info view
 	keymap     keymap
 	help       help.Model
 	marginLeft string
@@ -79,6 +83,7 @@ func initialModel(app *app.Application) model {
 		"List ignore patterns",
 		"Add new pattern",
 		"Remove patterns",
+		"Presets",
 	}
 
 	marginLeft := " "
@@ -119,9 +124,14 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			fallthrough
 		case addIgnorePatternView:
 			return handleInput(&m, msg)
+		case confirmCollectView:
+			if key.Matches(msg, m.keymap.confirm) {
+				m.handleConfirmCollect()
+				return m, nil
+			}
 		case infoMessageView:
 			if key.Matches(msg, m.keymap.viewCollectedFiles) {
-				if m.msg != "Successfully collected files" {
+				if m.result == nil {
 					break
 				}
 				m.lastView = m.view
@@ -148,6 +158,8 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			m.handleAdd()
 		case key.Matches(msg, m.keymap.delete):
 			m.handleDelete()
+		case key.Matches(msg, m.keymap.toggle):
+			m.handleToggle()
 		case key.Matches(msg, m.keymap.back):
 			m.handleBackspace()
 		case key.Matches(msg, m.keymap.selectionToggle):
@@ -171,6 +183,8 @@ func (m model) View() string {
 	switch m.view {
 	case infoMessageView:
 		sb.WriteString(m.renderInfoMessageView())
+	case confirmCollectView:
+		sb.WriteString(m.renderConfirmCollectView())
 	case listCollectedFilesView:
 		sb.WriteString(m.renderCollectedFilesView())
 	case listPathsView:
@@ -185,6 +199,8 @@ func (m model) View() string {
 		sb.WriteString(m.renderRemovePathsView())
 	case removeIgnorePatternsView:
 		sb.WriteString(m.renderRemoveIgnorePatternsView())
+	case presetsView:
+		sb.WriteString(m.renderPresetsView())
 	default:
 		sb.WriteString(m.renderMenuView())
 	}
@@ -3,9 +3,13 @@ package tui
 import (
 	"fmt"
 	"path/filepath"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/lipgloss/table"
+	"github.com/chtozamm/dotfiles-collector/internal/app"
 	"github.com/chtozamm/dotfiles-collector/internal/fileops"
 )
 
@@ -37,6 +41,80 @@ func (m model) renderAddIgnorePatternView() string {
 	)
 }
 
+// maxPlanEntries is the number of planned changes shown on the confirmation screen.
+const maxPlanEntries = 10
+
+func (m model) renderConfirmCollectView() string {
+	plan := m.pending.Plan
+
+	sb := strings.Builder{}
+	sb.WriteString(fmt.Sprintf("%s  Collecting files will make the following changes:\n\n", m.marginLeft))
+
+	shown := 0
+	for _, entry := range plan.Entries {
+		if entry.Action != fileops.ActionCreate && entry.Action != fileops.ActionOverwrite {
+			continue
+		}
+		if shown == maxPlanEntries {
+			break
+		}
+		sb.WriteString(fmt.Sprintf("%s  %-9s %s\n", m.marginLeft, entry.Action, entryStyle.Render(entry.Dst)))
+		shown++
+	}
+	if more := plan.Count(fileops.ActionCreate) + plan.Count(fileops.ActionOverwrite) - shown; more > 0 {
+		sb.WriteString(fmt.Sprintf("%s  ... and %d more\n", m.marginLeft, more))
+	}
+	if shown == 0 {
+		sb.WriteString(fmt.Sprintf("%s  Nothing to copy\n", m.marginLeft))
+	}
+	if errs := plan.Errors(); len(errs) > 0 {
+		sb.WriteString(fmt.Sprintf("\n%s  %d files can't be collected:\n\n%s\n", m.marginLeft, len(errs), m.renderErrorsTable(errs)))
+	}
+
+	total := m.pending.Total()
+	sb.WriteString(fmt.Sprintf(
+		"\n%s  %d to copy (%s), %d unchanged, %d skipped, %d ignored\n",
+		m.marginLeft,
+		total.Copied,
+		app.FormatSize(total.Bytes),
+		total.Unchanged,
+		total.Skipped,
+		total.Ignored,
+	))
+	return sb.String()
+}
+
+func (m model) renderResultTable(result *app.CollectResult) string {
+	t := table.New().
+		Border(lipgloss.NormalBorder()).
+		BorderStyle(lipgloss.NewStyle().Foreground(lipgloss.Color("#424243"))).
+		Headers("Source", "Copied", "Unchanged", "Skipped", "Ignored", "Deleted", "Failed", "Size")
+	for _, src := range result.Sources {
+		t.Row(
+			src.Path,
+			strconv.Itoa(src.Copied),
+			strconv.Itoa(src.Unchanged),
+			strconv.Itoa(src.Skipped),
+			strconv.Itoa(src.Ignored),
+			strconv.Itoa(src.Deleted),
+			strconv.Itoa(src.Failed),
+			app.FormatSize(src.Bytes),
+		)
+	}
+	return lipgloss.NewStyle().MarginLeft(len(m.marginLeft) + 2).Render(t.String())
+}
+
+func (m model) renderErrorsTable(errs fileops.Errors) string {
+	t := table.New().
+		Border(lipgloss.NormalBorder()).
+		BorderStyle(lipgloss.NewStyle().Foreground(lipgloss.Color("#424243"))).
+		Headers("Kind", "Path", "Error")
+	for _, err := range errs {
+		t.Row(err.Kind.String(), err.Path, err.Err.Error())
+	}
+	return lipgloss.NewStyle().MarginLeft(len(m.marginLeft) + 2).Render(t.String())
+}
+
 // func (m model) renderConfirmationPromptView() string {
 // 	sb := strings.Builder{}
 // 	sb.WriteString(fmt.Sprintf("  %v\n", m.msg))
@@ -46,6 +124,24 @@ func (m model) renderAddIgnorePatternView() string {
 func (m model) renderInfoMessageView() string {
 	sb := strings.Builder{}
 	sb.WriteString(fmt.Sprintf("  %s%v\n", m.marginLeft, m.msg))
+	if m.result != nil {
+		total := m.result.Total()
+		sb.WriteString(fmt.Sprintf(
+			"\n%s  %d copied (%s), %d unchanged in %s\n\n%s\n",
+			m.marginLeft,
+			total.Copied,
+			app.FormatSize(total.Bytes),
+			total.Unchanged,
+			m.result.Duration.Round(time.Millisecond),
+			m.renderResultTable(m.result),
+		))
+		for _, warning := range m.result.Warnings {
+			sb.WriteString(fmt.Sprintf("%s  Warning: %s\n", m.marginLeft, warning))
+		}
+		if errs := m.result.Plan.Errors(); len(errs) > 0 {
+			sb.WriteString(fmt.Sprintf("\n%s  %d files failed to copy:\n\n%s\n", m.marginLeft, len(errs), m.renderErrorsTable(errs)))
+		}
+	}
 	return sb.String()
 }
 
@@ -94,6 +190,13 @@ func (m *model) renderListPathsView() string {
 		m.view = infoMessageView
 		return m.msg
 	}
+	patterns, err := m.app.GetIgnorePatterns()
+	if err != nil {
+		m.msg = fmt.Sprintf("%s  Failed to get ignore patterns: %v\n", m.marginLeft, err)
+		m.lastView = m.view
+		m.view = infoMessageView
+		return m.msg
+	}
 
 	pathNames := make([]string, 0, len(paths))
 	for _, path := range paths {
@@ -116,16 +219,23 @@ func (m *model) renderListPathsView() string {
 			cursor = cursorStyle.Render(">")
 		}
 		if path.Subdir != "" {
-			sb.WriteString(fmt.Sprintf("%s%s %s ⟶  %s\n", m.marginLeft, cursor, entryStyle.Render(path.Path), path.Subdir))
+			sb.WriteString(fmt.Sprintf("%s%s %s ⟶  %s%s\n", m.marginLeft, cursor, renderEntry(path.Path, path.Enabled), path.Subdir, renderDisabled(path.Enabled)))
 		} else {
-			sb.WriteString(fmt.Sprintf("%s%s %s\n", m.marginLeft, cursor, entryStyle.Render(path.Path)))
+			sb.WriteString(fmt.Sprintf("%s%s %s%s\n", m.marginLeft, cursor, renderEntry(path.Path, path.Enabled), renderDisabled(path.Enabled)))
+		}
+
+		// Show ignore patterns scoped to the path below it
+		for _, pattern := range patterns {
+			if pattern.SourceID == path.ID {
+				sb.WriteString(fmt.Sprintf("%s    ignore %s%s%s\n", m.marginLeft, pattern.Pattern, renderPatternKind(pattern), renderDisabled(pattern.Enabled)))
+			}
 		}
 	}
 	return sb.String()
 }
 
 func (m *model) renderListIgnorePatternsView() string {
-	patterns, err := m.app.GetIgnorePatterns()
+	allPatterns, err := m.app.GetIgnorePatterns()
 	if err != nil {
 		m.msg = fmt.Sprintf("%s  Failed to get ignore patterns: %v\n", m.marginLeft, err)
 		m.lastView = m.view
@@ -133,7 +243,8 @@ func (m *model) renderListIgnorePatternsView() string {
 		return m.msg
 	}
 
-	m.options[m.view] = patterns
+	patterns := globalPatterns(allPatterns)
+	m.options[m.view] = patternKeys(patterns)
 
 	sb := strings.Builder{}
 	if len(patterns) > 0 {
@@ -149,7 +260,64 @@ func (m *model) renderListIgnorePatternsView() string {
 		if m.cursors[m.view] == i {
 			cursor = cursorStyle.Render(">")
 		}
-		sb.WriteString(fmt.Sprintf("%s%s %s\n", m.marginLeft, cursor, entryStyle.Render(pattern)))
+		sb.WriteString(fmt.Sprintf("%s%s %s%s%s\n", m.marginLeft, cursor, renderEntry(pattern.Pattern, pattern.Enabled), renderPatternKind(pattern), renderDisabled(pattern.Enabled)))
+	}
+	return sb.String()
+}
+
+// renderPatternKind returns a dimmed note for patterns that aren't regular expressions.
+func renderPatternKind(pattern app.IgnorePattern) string {
+	if pattern.Kind == fileops.PatternRegex {
+		return ""
+	}
+	return lipgloss.NewStyle().Foreground(lipgloss.Color("#424243")).Render(" (" + pattern.Kind.String() + ")")
+}
+
+// renderEntry returns the name of a path or a pattern, dimmed if it is disabled.
+func renderEntry(name string, enabled bool) string {
+	if !enabled {
+		return lipgloss.NewStyle().Foreground(lipgloss.Color("#424243")).Render(name)
+	}
+	return entryStyle.Render(name)
+}
+
+// renderDisabled returns a dimmed note for disabled paths and patterns.
+func renderDisabled(enabled bool) string {
+	if enabled {
+		return ""
+	}
+	return lipgloss.NewStyle().Foreground(lipgloss.Color("#424243")).Render(" (disabled)")
+}
+
+func (m *model) renderPresetsView() string {
+	presets, err := m.app.GetPresets()
+	if err != nil {
+		m.msg = fmt.Sprintf("%s  Failed to get presets: %v\n", m.marginLeft, err)
+		m.lastView = m.view
+		m.view = infoMessageView
+		return m.msg
+	}
+	presetNames := make([]string, 0, len(presets))
+	for _, preset := range presets {
+		presetNames = append(presetNames, preset.Name)
+	}
+	m.options[m.view] = presetNames
+
+	sb := strings.Builder{}
+	sb.WriteString(fmt.Sprintf("%s  Presets of ignore patterns:\n\n", m.marginLeft))
+	for i, preset := range presets {
+		cursor := " "
+		if m.cursors[m.view] == i {
+			cursor = cursorStyle.Render(">")
+		}
+		lb := lipgloss.NewStyle().Foreground(lipgloss.Color("#424243")).Render("[")
+		rb := lipgloss.NewStyle().Foreground(lipgloss.Color("#424243")).Render("]")
+		added := " "
+		if preset.Added {
+			added = lipgloss.NewStyle().Foreground(lipgloss.Color("#a6e3a1")).Render("x")
+		}
+		description := lipgloss.NewStyle().Foreground(lipgloss.Color("#424243")).Render(preset.Description)
+		sb.WriteString(fmt.Sprintf("%s%s %s%s%s %s %s\n", m.marginLeft, cursor, lb, added, rb, entryStyle.Render(fmt.Sprintf("%-15s", preset.Name)), description))
 	}
 	return sb.String()
 }
@@ -198,14 +366,15 @@ func (m *model) renderRemovePathsView() string {
 }
 
 func (m *model) renderRemoveIgnorePatternsView() string {
-	patterns, err := m.app.GetIgnorePatterns()
+	allPatterns, err := m.app.GetIgnorePatterns()
 	if err != nil {
 		m.msg = fmt.Sprintf("%s  Failed to get ignore patterns: %v\n", m.marginLeft, err)
 		m.lastView = m.view
 		m.view = infoMessageView
 		return m.msg
 	}
-	m.options[m.view] = patterns
+	patterns := globalPatterns(allPatterns)
+	m.options[m.view] = patternKeys(patterns)
 	sb := strings.Builder{}
 	if len(patterns) > 0 {
 		sb.WriteString(fmt.Sprintf("%s  Patterns for collector to ignore:\n\n", m.marginLeft))
@@ -223,10 +392,10 @@ func (m *model) renderRemoveIgnorePatternsView() string {
 		lb := lipgloss.NewStyle().Foreground(lipgloss.Color("#424243")).Render("[")
 		rb := lipgloss.NewStyle().Foreground(lipgloss.Color("#424243")).Render("]")
 		checked := " "
-		if m.choices[m.view][pattern] {
+		if m.choices[m.view][patternKey(pattern)] {
 			checked = lipgloss.NewStyle().Foreground(lipgloss.Color("#f38ba8")).Render("x")
 		}
-		sb.WriteString(fmt.Sprintf("%s%s %s%s%s %s\n", m.marginLeft, cursor, lb, checked, rb, entryStyle.Render(pattern)))
+		sb.WriteString(fmt.Sprintf("%s%s %s%s%s %s%s\n", m.marginLeft, cursor, lb, checked, rb, entryStyle.Render(pattern.Pattern), renderPatternKind(pattern)))
 	}
 	return sb.String()
 }
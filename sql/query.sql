@@ -5,19 +5,43 @@ SELECT * FROM collect_paths;
 SELECT * FROM collect_paths WHERE path = ?;
 
 -- name: AddCollectPath :exec
-INSERT INTO collect_paths (path, parent_dir) VALUES (?, ?);
+INSERT INTO collect_paths (path, portable, parent_dir, symlinks) VALUES (?, ?, ?, ?);
 
 -- name: RemoveCollectPath :exec
-DELETE FROM collect_paths WHERE path = ?;
+DELETE FROM collect_paths WHERE id = ?;
 
 -- name: GetIgnorePatterns :many
-SELECT * FROM ignore_patterns;
+SELECT * FROM ignore_patterns ORDER BY id;
 
 -- name: GetIgnorePattern :one
-SELECT * FROM ignore_patterns WHERE pattern = ?;
+SELECT * FROM ignore_patterns WHERE pattern = ? AND kind = ? AND source_id = ?;
 
 -- name: AddIgnorePattern :exec
-INSERT INTO ignore_patterns (pattern) VALUES (?);
+INSERT INTO ignore_patterns (pattern, kind, source_id, preset) VALUES (?, ?, ?, ?);
 
--- name: RemoveIgnorePattern :exec
-DELETE FROM ignore_patterns WHERE pattern = ?;
+-- name: GetPresets :many
+SELECT name FROM presets ORDER BY name;
+
+-- name: AddPreset :exec
+INSERT INTO presets (name) VALUES (?);
+
+-- name: RemovePreset :exec
+DELETE FROM presets WHERE name = ?;
+
+-- name: RemoveIgnorePattern :execrows
+DELETE FROM ignore_patterns WHERE pattern = ? AND kind = ? AND source_id = ?;
+
+-- name: RemoveSourceIgnorePatterns :exec
+DELETE FROM ignore_patterns WHERE source_id = ?;
+
+-- name: RemovePresetIgnorePatterns :exec
+DELETE FROM ignore_patterns WHERE preset = ?;
+
+-- name: SetCollectPathEnabled :exec
+UPDATE collect_paths SET enabled = ? WHERE id = ?;
+
+-- name: SetCollectPathPortable :exec
+UPDATE collect_paths SET portable = ? WHERE id = ?;
+
+-- name: SetIgnorePatternEnabled :execrows
+UPDATE ignore_patterns SET enabled = ? WHERE pattern = ? AND kind = ? AND source_id = ?;
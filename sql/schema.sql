@@ -2,11 +2,25 @@ CREATE TABLE collect_paths (
   id         INTEGER PRIMARY KEY,
   path       TEXT NOT NULL UNIQUE,
 	parent_dir TEXT NOT NULL,
+  symlinks   TEXT NOT NULL DEFAULT '',
+  enabled    BOOLEAN NOT NULL DEFAULT TRUE,
+  portable   TEXT NOT NULL DEFAULT '',
   created_at TEXT NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%fZ', 'now'))
 );
 
 CREATE TABLE ignore_patterns (
   id         INTEGER PRIMARY KEY,
-  pattern    TEXT NOT NULL UNIQUE,
+  pattern    TEXT NOT NULL,
+  kind       TEXT NOT NULL DEFAULT 'regex',
+  source_id  INTEGER NOT NULL DEFAULT 0,
+  preset     TEXT NOT NULL DEFAULT '',
+  enabled    BOOLEAN NOT NULL DEFAULT TRUE,
+  created_at TEXT NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%fZ', 'now')),
+  UNIQUE (pattern, kind, source_id)
+);
+
+CREATE TABLE presets (
+  id         INTEGER PRIMARY KEY,
+  name       TEXT NOT NULL UNIQUE,
   created_at TEXT NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%fZ', 'now'))
 );